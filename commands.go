@@ -0,0 +1,190 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/hkohlsaat/vtr/export"
+	"github.com/hkohlsaat/vtr/model"
+)
+
+// commands are the commands that can be run instead of the server,
+// e.g. "vtr prune --dry-run".
+var commands = map[string]func(args []string){
+	"export":    exportData,
+	"prune":     prune,
+	"reprocess": reprocess,
+}
+
+// prune removes old plan uploads and archived teachers and subjects according
+// to the retention policy.
+func prune(args []string) {
+	policy := retentionPolicy()
+	flags := flag.NewFlagSet("prune", flag.ExitOnError)
+	dryRun := flags.Bool("dry-run", false, "only report what would be removed")
+	flags.IntVar(&policy.Weeks, "weeks", policy.Weeks, "weeks to keep the last upload of each day")
+	flags.IntVar(&policy.FileDays, "file-days", policy.FileDays, "days to keep the uploaded files")
+	flags.IntVar(&policy.ArchiveDays, "archive-days", policy.ArchiveDays, "days to keep archived teachers and subjects, 0 keeps them")
+	flags.Parse(args)
+
+	report, err := policy.Prune(time.Now(), *dryRun)
+	fmt.Println(report)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error pruning plans: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// reprocess reads the plans of the given uploads again from their files.
+// With -all every upload still having its file is reprocessed.
+func reprocess(args []string) {
+	flags := flag.NewFlagSet("reprocess", flag.ExitOnError)
+	all := flags.Bool("all", false, "reprocess all uploads")
+	flags.Parse(args)
+
+	var uploads []model.PlanUpload
+	if *all {
+		for _, upload := range model.ReadAllPlanUploads() {
+			if upload.HasFile {
+				uploads = append(uploads, upload)
+			}
+		}
+	}
+	for _, arg := range flags.Args() {
+		id, err := strconv.ParseInt(arg, 10, 64)
+		upload := model.PlanUpload{ID: id}
+		if err != nil || !upload.Exists() {
+			fmt.Fprintf(os.Stderr, "no upload with id %s\n", arg)
+			os.Exit(1)
+		}
+		upload.Read()
+		uploads = append(uploads, upload)
+	}
+
+	failed := false
+	for _, upload := range uploads {
+		changes, err := upload.Reprocess()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "upload %d: %v\n", upload.ID, err)
+			failed = true
+			continue
+		}
+		after := upload
+		after.Read()
+		model.Audit(model.AuditSystem, model.AuditUpdate, model.AuditPlan, strconv.FormatInt(upload.ID, 10), upload, after)
+		fmt.Printf("upload %d from %s: %d changes\n", upload.ID, upload.Time.Format("2006-01-02 15:04"), len(changes))
+	}
+	if failed {
+		os.Exit(1)
+	}
+}
+
+// pruneRegularly prunes old plan uploads and archived teachers and subjects
+// once a day.
+func pruneRegularly(policy model.RetentionPolicy) {
+	for {
+		report, err := policy.Prune(time.Now(), false)
+		if err != nil {
+			log.Printf("error pruning plans: %v\n", err)
+		} else if len(report.Deleted) > 0 || len(report.FilesDropped) > 0 ||
+			len(report.PurgedTeachers) > 0 || len(report.PurgedSubjects) > 0 {
+			log.Printf("pruning plans: %v\n", report)
+		}
+		time.Sleep(24 * time.Hour)
+	}
+}
+
+// retentionPolicy returns the default retention policy changed by the
+// environment variables VTR_RETENTION_WEEKS, VTR_RETENTION_FILE_DAYS and
+// VTR_RETENTION_ARCHIVE_DAYS.
+func retentionPolicy() model.RetentionPolicy {
+	policy := model.DefaultRetention
+	if weeks, err := strconv.Atoi(os.Getenv("VTR_RETENTION_WEEKS")); err == nil {
+		policy.Weeks = weeks
+	}
+	if days, err := strconv.Atoi(os.Getenv("VTR_RETENTION_FILE_DAYS")); err == nil {
+		policy.FileDays = days
+	}
+	if days, err := strconv.Atoi(os.Getenv("VTR_RETENTION_ARCHIVE_DAYS")); err == nil {
+		policy.ArchiveDays = days
+	}
+	return policy
+}
+
+// exportData writes a dataset as CSV or XLSX file, e.g.
+// "vtr export -format xlsx -from 2016-10-17 -to 2016-10-21 plan".
+func exportData(args []string) {
+	flags := flag.NewFlagSet("export", flag.ExitOnError)
+	format := flags.String("format", "csv", `"csv" or "xlsx"`)
+	separator := flags.String("separator", ";", `field separator of CSV files, "tab" for tabs`)
+	bom := flags.Bool("bom", true, "start CSV files with a byte order mark for Excel")
+	planID := flags.Int64("plan", 0, "id of the upload to export, the last one if not set")
+	from := flags.String("from", "", "first day to export (YYYY-MM-DD)")
+	to := flags.String("to", "", "last day to export (YYYY-MM-DD), the first day if not set")
+	output := flags.String("o", "", "file to write to instead of the standard output")
+	flags.Usage = func() {
+		fmt.Fprintf(os.Stderr, "usage: vtr export [flags] %s\n", strings.Join(export.Datasets, "|"))
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+	if flags.NArg() != 1 {
+		flags.Usage()
+		os.Exit(2)
+	}
+
+	query := export.Query{Dataset: flags.Arg(0), PlanID: *planID}
+	var err error
+	if *from != "" {
+		query.From, err = time.Parse("2006-01-02", *from)
+	}
+	if *to != "" && err == nil {
+		query.To, err = time.Parse("2006-01-02", *to)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "invalid day: %v\n", err)
+		os.Exit(2)
+	}
+
+	table, err := export.Read(query)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error exporting %s: %v\n", query.Dataset, err)
+		os.Exit(1)
+	}
+
+	var w io.Writer = os.Stdout
+	if *output != "" {
+		file, err := os.Create(*output)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error creating %s: %v\n", *output, err)
+			os.Exit(1)
+		}
+		defer file.Close()
+		w = file
+	}
+
+	switch *format {
+	case "csv":
+		options := export.CSVOptions{BOM: *bom}
+		options.Separator, _ = utf8.DecodeRuneInString(*separator)
+		if *separator == "tab" {
+			options.Separator = '\t'
+		}
+		err = export.WriteCSV(w, table, options)
+	case "xlsx":
+		err = export.WriteXLSX(w, table)
+	default:
+		fmt.Fprintf(os.Stderr, "unknown format %s\n", *format)
+		os.Exit(2)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error writing %s: %v\n", query.Dataset, err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,109 @@
+package controller
+
+import (
+	"fmt"
+	"html"
+	"net/http"
+
+	"github.com/hkohlsaat/vtr/model"
+	"github.com/julienschmidt/httprouter"
+)
+
+// CreateTeacherAlias adds an alias to a teacher and serves the edit form again.
+func CreateTeacherAlias(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+	redirected, session := ensureLoggedIn(w, r)
+	if redirected {
+		return
+	}
+
+	teacher := model.Teacher{Short: html.EscapeString(params.ByName("short"))}
+	if !teacher.Exists() {
+		http.NotFound(w, r)
+		return
+	}
+	teacher.Read()
+
+	r.ParseForm()
+	alias := model.Alias{Kind: model.AliasTeacher, Alias: html.EscapeString(r.Form.Get("alias")), Short: teacher.Short}
+	showTeacherEdit(w, teacher, simpleMessage(createAlias(session.Username, alias)))
+}
+
+// DeleteTeacherAlias deletes an alias of a teacher and serves the URL undoing
+// it, see writeUndo.
+func DeleteTeacherAlias(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+	redirected, session := ensureLoggedIn(w, r)
+	if redirected {
+		return
+	}
+
+	deleteAlias(w, r, session.Username, model.Alias{Kind: model.AliasTeacher, Alias: params.ByName("alias"), Short: params.ByName("short")})
+}
+
+// CreateSubjectAlias adds an alias to a subject and serves the edit form again.
+func CreateSubjectAlias(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+	redirected, session := ensureLoggedIn(w, r)
+	if redirected {
+		return
+	}
+
+	subject := model.Subject{Short: html.EscapeString(params.ByName("short"))}
+	if !subject.Exists() {
+		http.NotFound(w, r)
+		return
+	}
+	subject.Read()
+
+	r.ParseForm()
+	alias := model.Alias{Kind: model.AliasSubject, Alias: html.EscapeString(r.Form.Get("alias")), Short: subject.Short}
+	showSubjectEdit(w, subject, simpleMessage(createAlias(session.Username, alias)))
+}
+
+// DeleteSubjectAlias deletes an alias of a subject and serves the URL undoing
+// it, see writeUndo.
+func DeleteSubjectAlias(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+	redirected, session := ensureLoggedIn(w, r)
+	if redirected {
+		return
+	}
+
+	deleteAlias(w, r, session.Username, model.Alias{Kind: model.AliasSubject, Alias: params.ByName("alias"), Short: params.ByName("short")})
+}
+
+// createAlias validates and creates the alias for the actor. The alias is
+// removed from the unknown shorts. The message tells what happened.
+func createAlias(actor string, alias model.Alias) (message string, created bool) {
+	taken := false
+	if alias.Kind == model.AliasTeacher {
+		teacher := model.Teacher{Short: alias.Alias}
+		taken = teacher.Exists()
+	} else {
+		subject := model.Subject{Short: alias.Alias}
+		taken = subject.Exists()
+	}
+
+	switch {
+	case len(alias.Alias) == 0:
+		return "Der Alias ist zu kurz.", false
+	case alias.Alias == alias.Short || taken:
+		return fmt.Sprintf("%s ist selbst ein Kürzel.", alias.Alias), false
+	case alias.Exists():
+		return fmt.Sprintf("%s ist schon ein Alias.", alias.Alias), false
+	}
+
+	alias.Create()
+	model.Audit(actor, model.AuditCreate, model.AuditAlias, alias.Alias, nil, alias)
+	deleteUnknown(alias.Kind, alias.Alias)
+	return fmt.Sprintf("%s steht jetzt für %s.", alias.Alias, alias.Short), true
+}
+
+// deleteAlias deletes the alias for the actor if it belongs to the short.
+func deleteAlias(w http.ResponseWriter, r *http.Request, actor string, alias model.Alias) {
+	for _, a := range model.ReadAliases(alias.Kind, alias.Short) {
+		if a.Alias == alias.Alias {
+			alias.Delete()
+			writeUndo(w, model.Audit(actor, model.AuditDelete, model.AuditAlias, alias.Alias, alias, nil))
+			return
+		}
+	}
+	http.NotFound(w, r)
+}
@@ -0,0 +1,399 @@
+package controller
+
+import (
+	"encoding/json"
+	"fmt"
+	"html"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/hkohlsaat/vtr/model"
+	"github.com/julienschmidt/httprouter"
+)
+
+// The types below are the objects of the versioned JSON API at /api/v1/. They
+// are kept apart from the model, so changes to the model don't change the API.
+// Every change to them changes the OpenAPI document, see openapi.go.
+
+// apiPlanInfo describes an uploaded plan.
+type apiPlanInfo struct {
+	ID        int64     `json:"id"`
+	Uploaded  time.Time `json:"uploaded"`
+	Confirmed time.Time `json:"confirmed"`
+	Hash      string    `json:"hash"`
+}
+
+// apiPlan is an uploaded plan with its days.
+type apiPlan struct {
+	apiPlanInfo
+	Created time.Time `json:"created"`
+	Days    []apiDay  `json:"days"`
+}
+
+// apiDay holds the news and substitutions of one day.
+type apiDay struct {
+	Date          string            `json:"date" format:"date"`
+	News          []string          `json:"news"`
+	Substitutions []apiSubstitution `json:"substitutions"`
+}
+
+// apiSubstitution is one substitution. The date is only set when
+// substitutions are listed without their day.
+type apiSubstitution struct {
+	ID                string       `json:"id"`
+	Date              string       `json:"date,omitempty" format:"date"`
+	Period            string       `json:"period"`
+	Classes           []string     `json:"classes"`
+	Kind              string       `json:"kind"`
+	Text              string       `json:"text"`
+	SubstituteTeacher *apiTeacher  `json:"substitute_teacher,omitempty"`
+	AbsentTeacher     *apiTeacher  `json:"absent_teacher,omitempty"`
+	Subject           *apiSubject  `json:"subject,omitempty"`
+	TaskProviders     []apiTeacher `json:"task_providers"`
+	Rooms             []string     `json:"rooms"`
+	MovedTo           string       `json:"moved_to,omitempty"`
+	MovedFrom         string       `json:"moved_from,omitempty"`
+}
+
+// apiTeacher is a teacher. Name and sex are empty for unknown shorts.
+type apiTeacher struct {
+	Short       string `json:"short"`
+	Name        string `json:"name"`
+	Sex         string `json:"sex" enum:"m,w,"`
+	DisplayName string `json:"display_name"`
+	// Inactive teachers are no longer in the Untis export.
+	Inactive bool `json:"inactive"`
+}
+
+// apiSubject is a subject. The name is empty for unknown shorts.
+type apiSubject struct {
+	Short      string `json:"short"`
+	Name       string `json:"name"`
+	SplitClass bool   `json:"split_class"`
+}
+
+// apiPagination tells which part of a list is returned.
+type apiPagination struct {
+	Page    int `json:"page"`
+	PerPage int `json:"per_page"`
+	Total   int `json:"total"`
+}
+
+type apiPlanList struct {
+	apiPagination
+	Items []apiPlanInfo `json:"items"`
+}
+
+type apiSubstitutionList struct {
+	apiPagination
+	Items []apiSubstitution `json:"items"`
+}
+
+type apiTeacherList struct {
+	apiPagination
+	Items []apiTeacher `json:"items"`
+}
+
+type apiSubjectList struct {
+	apiPagination
+	Items []apiSubject `json:"items"`
+}
+
+// apiError is the body of every response with an error status.
+type apiError struct {
+	Error apiErrorDetail `json:"error"`
+}
+
+type apiErrorDetail struct {
+	Status  int    `json:"status"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+const (
+	defaultPerPage = 50
+	maxPerPage     = 200
+)
+
+// GetAPIPlans serves the list of uploaded plans, the newest first. Old plans
+// name teachers the public plan doesn't show anymore, so it needs a token.
+func GetAPIPlans(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	if !ensureAPIToken(w, r) {
+		return
+	}
+	pagination, ok := readPagination(w, r)
+	if !ok {
+		return
+	}
+
+	uploads := model.ReadAllPlanUploads()
+	from, to := pagination.bounds(len(uploads))
+	list := apiPlanList{apiPagination: pagination, Items: []apiPlanInfo{}}
+	for _, upload := range uploads[from:to] {
+		list.Items = append(list.Items, newAPIPlanInfo(upload))
+	}
+	writeAPI(w, http.StatusOK, list)
+}
+
+// GetAPIPlan serves one plan. The id "latest" stands for the newest plan.
+func GetAPIPlan(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+	upload, plan, ok := readAPIPlan(w, r, params.ByName("id"))
+	if !ok {
+		return
+	}
+
+	result := apiPlan{apiPlanInfo: newAPIPlanInfo(upload), Created: plan.Created, Days: []apiDay{}}
+	for _, part := range plan.Parts {
+		day := apiDay{Date: part.Day.Format("2006-01-02"), News: part.News, Substitutions: []apiSubstitution{}}
+		if day.News == nil {
+			day.News = []string{}
+		}
+		for _, s := range part.Substitutions {
+			day.Substitutions = append(day.Substitutions, newAPISubstitution(s))
+		}
+		result.Days = append(result.Days, day)
+	}
+	writeAPI(w, http.StatusOK, result)
+}
+
+// GetAPISubstitutions serves the substitutions of one plan. They can be
+// filtered by class and date (YYYY-MM-DD).
+func GetAPISubstitutions(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+	pagination, ok := readPagination(w, r)
+	if !ok {
+		return
+	}
+	_, plan, ok := readAPIPlan(w, r, params.ByName("id"))
+	if !ok {
+		return
+	}
+
+	class, date := r.URL.Query().Get("class"), r.URL.Query().Get("date")
+	if _, err := time.Parse("2006-01-02", date); date != "" && err != nil {
+		writeAPIError(w, http.StatusBadRequest, "invalid_parameter", "date must be formatted as YYYY-MM-DD")
+		return
+	}
+
+	substitutions := []apiSubstitution{}
+	for _, part := range plan.Parts {
+		day := part.Day.Format("2006-01-02")
+		if date != "" && date != day {
+			continue
+		}
+		for _, s := range part.Substitutions {
+			if class != "" && !s.HasClass(class) {
+				continue
+			}
+			substitution := newAPISubstitution(s)
+			substitution.Date = day
+			substitutions = append(substitutions, substitution)
+		}
+	}
+
+	from, to := pagination.bounds(len(substitutions))
+	writeAPI(w, http.StatusOK, apiSubstitutionList{apiPagination: pagination, Items: substitutions[from:to]})
+}
+
+// GetAPITeachers serves the list of all teachers.
+func GetAPITeachers(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	if !ensureAPIToken(w, r) {
+		return
+	}
+	pagination, ok := readPagination(w, r)
+	if !ok {
+		return
+	}
+
+	teachers := model.ReadAllTeachers()
+	from, to := pagination.bounds(len(teachers))
+	list := apiTeacherList{apiPagination: pagination, Items: []apiTeacher{}}
+	for _, teacher := range teachers[from:to] {
+		list.Items = append(list.Items, *newAPITeacher(teacher))
+	}
+	writeAPI(w, http.StatusOK, list)
+}
+
+// GetAPITeacher serves one teacher.
+func GetAPITeacher(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+	if !ensureAPIToken(w, r) {
+		return
+	}
+
+	teacher := model.Teacher{Short: html.EscapeString(params.ByName("short"))}
+	if !teacher.Exists() {
+		writeAPIError(w, http.StatusNotFound, "not_found", fmt.Sprintf("there is no teacher %q", teacher.Short))
+		return
+	}
+	teacher.Read()
+	writeAPI(w, http.StatusOK, newAPITeacher(teacher))
+}
+
+// GetAPISubjects serves the list of all subjects.
+func GetAPISubjects(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	if !ensureAPIToken(w, r) {
+		return
+	}
+	pagination, ok := readPagination(w, r)
+	if !ok {
+		return
+	}
+
+	subjects := model.ReadAllSubjects()
+	from, to := pagination.bounds(len(subjects))
+	list := apiSubjectList{apiPagination: pagination, Items: []apiSubject{}}
+	for _, subject := range subjects[from:to] {
+		list.Items = append(list.Items, *newAPISubject(subject))
+	}
+	writeAPI(w, http.StatusOK, list)
+}
+
+// GetAPISubject serves one subject.
+func GetAPISubject(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+	if !ensureAPIToken(w, r) {
+		return
+	}
+
+	subject := model.Subject{Short: html.EscapeString(params.ByName("short"))}
+	if !subject.Exists() {
+		writeAPIError(w, http.StatusNotFound, "not_found", fmt.Sprintf("there is no subject %q", subject.Short))
+		return
+	}
+	subject.Read()
+	writeAPI(w, http.StatusOK, newAPISubject(subject))
+}
+
+// readAPIPlan reads the plan with the id, which may be "latest". An error is
+// written if there is no such plan.
+func readAPIPlan(w http.ResponseWriter, r *http.Request, id string) (model.PlanUpload, *model.Plan, bool) {
+	var upload model.PlanUpload
+	if id == "latest" {
+		last, ok := model.LastPlanUpload()
+		if !ok {
+			writeAPIError(w, http.StatusNotFound, "not_found", "there is no plan yet")
+			return upload, nil, false
+		}
+		upload = last
+	} else {
+		// Only the newest plan is public, like on /plan. The token is checked
+		// first, so it isn't told which older plans exist.
+		if !ensureAPIToken(w, r) {
+			return upload, nil, false
+		}
+		n, err := strconv.ParseInt(id, 10, 64)
+		upload.ID = n
+		if err != nil || !upload.Exists() {
+			writeAPIError(w, http.StatusNotFound, "not_found", fmt.Sprintf("there is no plan %q", id))
+			return upload, nil, false
+		}
+		upload.Read()
+	}
+
+	plan, err := upload.Plan()
+	if err != nil {
+		log.Printf("error reading plan %d: %v\n", upload.ID, err)
+		writeAPIError(w, http.StatusInternalServerError, "internal", "the plan could not be read")
+		return upload, nil, false
+	}
+	plan.Resolve()
+	return upload, plan, true
+}
+
+// readPagination reads the page and per_page parameters. An error is written
+// if they are invalid.
+func readPagination(w http.ResponseWriter, r *http.Request) (apiPagination, bool) {
+	pagination := apiPagination{Page: 1, PerPage: defaultPerPage}
+	for name, value := range map[string]*int{"page": &pagination.Page, "per_page": &pagination.PerPage} {
+		param := r.URL.Query().Get(name)
+		if param == "" {
+			continue
+		}
+		n, err := strconv.Atoi(param)
+		if err != nil || n < 1 {
+			writeAPIError(w, http.StatusBadRequest, "invalid_parameter", name+" must be a positive number")
+			return pagination, false
+		}
+		*value = n
+	}
+	if pagination.PerPage > maxPerPage {
+		pagination.PerPage = maxPerPage
+	}
+	return pagination, true
+}
+
+// bounds sets the total and returns the range of the list's items on the page.
+func (p *apiPagination) bounds(total int) (from, to int) {
+	p.Total = total
+	from = (p.Page - 1) * p.PerPage
+	if from > total {
+		from = total
+	}
+	to = from + p.PerPage
+	if to > total {
+		to = total
+	}
+	return from, to
+}
+
+func newAPIPlanInfo(upload model.PlanUpload) apiPlanInfo {
+	return apiPlanInfo{ID: upload.ID, Uploaded: upload.Time, Confirmed: upload.Confirmed, Hash: upload.Hash}
+}
+
+func newAPISubstitution(s model.Substitution) apiSubstitution {
+	substitution := apiSubstitution{
+		ID:                s.ID,
+		Period:            s.Period,
+		Classes:           s.Classes(),
+		Kind:              s.Kind,
+		Text:              s.Text,
+		SubstituteTeacher: newAPITeacher(s.SubstTeacher),
+		AbsentTeacher:     newAPITeacher(s.InstdTeacher),
+		Subject:           newAPISubject(s.InstdSubject),
+		TaskProviders:     []apiTeacher{},
+		Rooms:             s.Rooms,
+		MovedTo:           s.MovedTo,
+		MovedFrom:         s.MovedFrom,
+	}
+	if substitution.Classes == nil {
+		substitution.Classes = []string{}
+	}
+	if substitution.Rooms == nil {
+		substitution.Rooms = []string{}
+	}
+	for _, teacher := range s.TaskProviders {
+		substitution.TaskProviders = append(substitution.TaskProviders, *newAPITeacher(teacher))
+	}
+	return substitution
+}
+
+// newAPITeacher returns nil for teachers without short.
+func newAPITeacher(teacher model.Teacher) *apiTeacher {
+	if teacher.Short == "" {
+		return nil
+	}
+	return &apiTeacher{Short: teacher.Short, Name: teacher.Name, Sex: teacher.Sex, DisplayName: teacher.FullName(), Inactive: teacher.Inactive}
+}
+
+// newAPISubject returns nil for subjects without short.
+func newAPISubject(subject model.Subject) *apiSubject {
+	if subject.Short == "" {
+		return nil
+	}
+	return &apiSubject{Short: subject.Short, Name: subject.Name, SplitClass: subject.SplitClass}
+}
+
+// writeAPI writes the value as JSON response.
+func writeAPI(w http.ResponseWriter, status int, value interface{}) {
+	w.Header().Set("content-type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(value); err != nil {
+		log.Printf("error: %v\n", err)
+	}
+}
+
+// writeAPIError writes an error object. The code is a short machine readable
+// description of the error, e.g. "not_found".
+func writeAPIError(w http.ResponseWriter, status int, code, message string) {
+	writeAPI(w, status, apiError{apiErrorDetail{Status: status, Code: code, Message: message}})
+}
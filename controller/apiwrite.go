@@ -0,0 +1,336 @@
+package controller
+
+import (
+	"encoding/json"
+	"fmt"
+	"html"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/hkohlsaat/vtr/model"
+	"github.com/julienschmidt/httprouter"
+)
+
+// The handlers below change teachers, subjects and the lists of unknown shorts.
+// They need a token created at /tokens, sent as "Authorization: Bearer <token>".
+
+// apiTeacherInput is a teacher to create or update.
+type apiTeacherInput struct {
+	Short string `json:"short"`
+	Name  string `json:"name"`
+	Sex   string `json:"sex" enum:"m,w"`
+}
+
+// apiSubjectInput is a subject to create or update.
+type apiSubjectInput struct {
+	Short      string `json:"short"`
+	Name       string `json:"name"`
+	SplitClass bool   `json:"split_class"`
+}
+
+// escape escapes the values like the forms store them.
+func (input *apiTeacherInput) escape() {
+	input.Short, input.Name, input.Sex = html.EscapeString(input.Short), html.EscapeString(input.Name), html.EscapeString(input.Sex)
+}
+
+// escape escapes the values like the forms store them.
+func (input *apiSubjectInput) escape() {
+	input.Short, input.Name = html.EscapeString(input.Short), html.EscapeString(input.Name)
+}
+
+// apiUnknown is a short found in a plan which is neither a known teacher nor subject.
+type apiUnknown struct {
+	Short string `json:"short"`
+}
+
+type apiUnknownList struct {
+	Items []apiUnknown `json:"items"`
+}
+
+// CreateAPITeacher creates a teacher.
+func CreateAPITeacher(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	var input apiTeacherInput
+	actor, ok := ensureAPIActor(w, r)
+	if !ok || !readAPIBody(w, r, &input) {
+		return
+	}
+	input.escape()
+	if !validAPITeacher(w, input) {
+		return
+	}
+
+	teacher := model.Teacher{Short: input.Short, Name: input.Name, Sex: input.Sex}
+	if teacher.Exists() {
+		writeAPIError(w, http.StatusConflict, "exists", fmt.Sprintf("there is a teacher %q already", teacher.Short))
+		return
+	}
+	teacher.Create()
+	model.Audit(actor, model.AuditCreate, model.AuditTeacher, teacher.Short, nil, teacher)
+	unknown := model.UnknownTeacher{Short: teacher.Short}
+	unknown.Delete()
+
+	w.Header().Set("location", "/api/v1/teachers/"+teacher.Short)
+	writeAPI(w, http.StatusCreated, newAPITeacher(teacher))
+}
+
+// UpdateAPITeacher updates the teacher or creates it if there isn't a teacher
+// with the short yet. The short is changed if the body holds another one.
+func UpdateAPITeacher(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+	var input apiTeacherInput
+	actor, ok := ensureAPIActor(w, r)
+	if !ok || !readAPIBody(w, r, &input) {
+		return
+	}
+	input.escape()
+	short := html.EscapeString(params.ByName("short"))
+	if input.Short == "" {
+		input.Short = short
+	}
+	if !validAPITeacher(w, input) {
+		return
+	}
+
+	teacher := model.Teacher{Short: input.Short, Name: input.Name, Sex: input.Sex}
+	existing := model.Teacher{Short: short}
+	switch {
+	case !existing.Exists() && input.Short != short:
+		writeAPIError(w, http.StatusNotFound, "not_found", fmt.Sprintf("there is no teacher %q", short))
+	case !existing.Exists():
+		teacher.Create()
+		model.Audit(actor, model.AuditCreate, model.AuditTeacher, teacher.Short, nil, teacher)
+		unknown := model.UnknownTeacher{Short: teacher.Short}
+		unknown.Delete()
+		w.Header().Set("location", "/api/v1/teachers/"+teacher.Short)
+		writeAPI(w, http.StatusCreated, newAPITeacher(teacher))
+	default:
+		existing.Read()
+		if err := teacher.UpdateShort(short); err == model.ErrShortTaken {
+			writeAPIError(w, http.StatusConflict, "exists", fmt.Sprintf("there is a teacher or alias %q already", teacher.Short))
+			return
+		} else if err != nil {
+			log.Printf("error renaming teacher %s: %v\n", short, err)
+			writeAPIError(w, http.StatusInternalServerError, "internal", "the teacher couldn't be saved")
+			return
+		}
+		teacher.Read()
+		model.Audit(actor, model.AuditUpdate, model.AuditTeacher, short, existing, teacher)
+		writeAPI(w, http.StatusOK, newAPITeacher(teacher))
+	}
+}
+
+// DeleteAPITeacher moves a teacher to the trash.
+func DeleteAPITeacher(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+	actor, ok := ensureAPIActor(w, r)
+	if !ok {
+		return
+	}
+
+	teacher := model.Teacher{Short: html.EscapeString(params.ByName("short"))}
+	if !teacher.Exists() {
+		writeAPIError(w, http.StatusNotFound, "not_found", fmt.Sprintf("there is no teacher %q", teacher.Short))
+		return
+	}
+	teacher.Read()
+	before := teacher
+	teacher.Archive(time.Now(), time.Time{})
+	model.Audit(actor, model.AuditArchive, model.AuditTeacher, teacher.Short, before, teacher)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// CreateAPISubject creates a subject.
+func CreateAPISubject(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	var input apiSubjectInput
+	actor, ok := ensureAPIActor(w, r)
+	if !ok || !readAPIBody(w, r, &input) {
+		return
+	}
+	input.escape()
+	if !validAPISubject(w, input) {
+		return
+	}
+
+	subject := model.Subject{Short: input.Short, Name: input.Name, SplitClass: input.SplitClass}
+	if subject.Exists() {
+		writeAPIError(w, http.StatusConflict, "exists", fmt.Sprintf("there is a subject %q already", subject.Short))
+		return
+	}
+	subject.Create()
+	model.Audit(actor, model.AuditCreate, model.AuditSubject, subject.Short, nil, subject)
+	unknown := model.UnknownSubject{Short: subject.Short}
+	unknown.Delete()
+
+	w.Header().Set("location", "/api/v1/subjects/"+subject.Short)
+	writeAPI(w, http.StatusCreated, newAPISubject(subject))
+}
+
+// UpdateAPISubject updates the subject or creates it if there isn't a subject
+// with the short yet. The short is changed if the body holds another one.
+func UpdateAPISubject(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+	var input apiSubjectInput
+	actor, ok := ensureAPIActor(w, r)
+	if !ok || !readAPIBody(w, r, &input) {
+		return
+	}
+	input.escape()
+	short := html.EscapeString(params.ByName("short"))
+	if input.Short == "" {
+		input.Short = short
+	}
+	if !validAPISubject(w, input) {
+		return
+	}
+
+	subject := model.Subject{Short: input.Short, Name: input.Name, SplitClass: input.SplitClass}
+	existing := model.Subject{Short: short}
+	switch {
+	case !existing.Exists() && input.Short != short:
+		writeAPIError(w, http.StatusNotFound, "not_found", fmt.Sprintf("there is no subject %q", short))
+	case !existing.Exists():
+		subject.Create()
+		model.Audit(actor, model.AuditCreate, model.AuditSubject, subject.Short, nil, subject)
+		unknown := model.UnknownSubject{Short: subject.Short}
+		unknown.Delete()
+		w.Header().Set("location", "/api/v1/subjects/"+subject.Short)
+		writeAPI(w, http.StatusCreated, newAPISubject(subject))
+	default:
+		existing.Read()
+		if err := subject.UpdateShort(short); err == model.ErrShortTaken {
+			writeAPIError(w, http.StatusConflict, "exists", fmt.Sprintf("there is a subject or alias %q already", subject.Short))
+			return
+		} else if err != nil {
+			log.Printf("error renaming subject %s: %v\n", short, err)
+			writeAPIError(w, http.StatusInternalServerError, "internal", "the subject couldn't be saved")
+			return
+		}
+		subject.Read()
+		model.Audit(actor, model.AuditUpdate, model.AuditSubject, short, existing, subject)
+		writeAPI(w, http.StatusOK, newAPISubject(subject))
+	}
+}
+
+// DeleteAPISubject moves a subject to the trash.
+func DeleteAPISubject(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+	actor, ok := ensureAPIActor(w, r)
+	if !ok {
+		return
+	}
+
+	subject := model.Subject{Short: html.EscapeString(params.ByName("short"))}
+	if !subject.Exists() {
+		writeAPIError(w, http.StatusNotFound, "not_found", fmt.Sprintf("there is no subject %q", subject.Short))
+		return
+	}
+	subject.Read()
+	before := subject
+	subject.Archive(time.Now(), time.Time{})
+	model.Audit(actor, model.AuditArchive, model.AuditSubject, subject.Short, before, subject)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GetAPIUnknownTeachers serves the shorts found in plans which aren't known teachers.
+func GetAPIUnknownTeachers(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	if !ensureAPIToken(w, r) {
+		return
+	}
+
+	list := apiUnknownList{Items: []apiUnknown{}}
+	for _, unknown := range model.ReadAllUnknownTeachers() {
+		list.Items = append(list.Items, apiUnknown{Short: unknown.Short})
+	}
+	writeAPI(w, http.StatusOK, list)
+}
+
+// DeleteAPIUnknownTeacher removes a short from the unknown teachers.
+func DeleteAPIUnknownTeacher(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+	actor, ok := ensureAPIActor(w, r)
+	if !ok {
+		return
+	}
+
+	unknown := model.UnknownTeacher{Short: params.ByName("short")}
+	if unknown.Read() {
+		unknown.Delete()
+		model.Audit(actor, model.AuditDelete, model.AuditUnknownTeacher, unknown.Short, unknown, nil)
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GetAPIUnknownSubjects serves the shorts found in plans which aren't known subjects.
+func GetAPIUnknownSubjects(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	if !ensureAPIToken(w, r) {
+		return
+	}
+
+	list := apiUnknownList{Items: []apiUnknown{}}
+	for _, unknown := range model.ReadAllUnknownSubjects() {
+		list.Items = append(list.Items, apiUnknown{Short: unknown.Short})
+	}
+	writeAPI(w, http.StatusOK, list)
+}
+
+// DeleteAPIUnknownSubject removes a short from the unknown subjects.
+func DeleteAPIUnknownSubject(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+	actor, ok := ensureAPIActor(w, r)
+	if !ok {
+		return
+	}
+
+	unknown := model.UnknownSubject{Short: params.ByName("short")}
+	if unknown.Read() {
+		unknown.Delete()
+		model.Audit(actor, model.AuditDelete, model.AuditUnknownSubject, unknown.Short, unknown, nil)
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ensureAPIToken tells whether the request carries a valid token. An error is
+// written if it doesn't.
+func ensureAPIToken(w http.ResponseWriter, r *http.Request) bool {
+	_, ok := ensureAPIActor(w, r)
+	return ok
+}
+
+// ensureAPIActor is ensureAPIToken for changes. It returns the actor recorded
+// in the audit log, "API: " followed by the token's name.
+func ensureAPIActor(w http.ResponseWriter, r *http.Request) (actor string, ok bool) {
+	authorization := r.Header.Get("Authorization")
+	if secret := strings.TrimPrefix(authorization, "Bearer "); secret != authorization {
+		if token, ok := model.AuthenticateAPIToken(strings.TrimSpace(secret)); ok {
+			return "API: " + token.Name, true
+		}
+	}
+
+	w.Header().Set("www-authenticate", `Bearer realm="vtr"`)
+	writeAPIError(w, http.StatusUnauthorized, "unauthorized", "a valid token is needed")
+	return "", false
+}
+
+// readAPIBody reads the JSON body into value. An error is written if the body
+// can't be read.
+func readAPIBody(w http.ResponseWriter, r *http.Request, value interface{}) bool {
+	decoder := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<20))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(value); err != nil {
+		writeAPIError(w, http.StatusBadRequest, "invalid_body", err.Error())
+		return false
+	}
+	return true
+}
+
+func validAPITeacher(w http.ResponseWriter, input apiTeacherInput) bool {
+	valid, message := validateTeacherData(input.Short, input.Name, input.Sex)
+	if !valid {
+		writeAPIError(w, http.StatusUnprocessableEntity, "invalid_teacher", message)
+	}
+	return valid
+}
+
+func validAPISubject(w http.ResponseWriter, input apiSubjectInput) bool {
+	valid, message := validateSubjectData(input.Short, input.Name, input.SplitClass)
+	if !valid {
+		writeAPIError(w, http.StatusUnprocessableEntity, "invalid_subject", message)
+	}
+	return valid
+}
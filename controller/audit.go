@@ -0,0 +1,116 @@
+package controller
+
+import (
+	"html/template"
+	"log"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/hkohlsaat/vtr/export"
+	"github.com/hkohlsaat/vtr/model"
+	"github.com/julienschmidt/httprouter"
+)
+
+// maxAuditEntries is the number of audit entries shown at most.
+const maxAuditEntries = 500
+
+// auditActions and auditEntities are the actions and entities to filter the
+// audit log by.
+var (
+	auditActions  = []string{model.AuditCreate, model.AuditUpdate, model.AuditDelete, model.AuditArchive, model.AuditRestore, model.AuditUpload}
+	auditEntities = []string{model.AuditTeacher, model.AuditSubject, model.AuditAlias, model.AuditUser, model.AuditPlan,
+		model.AuditToken, model.AuditDisplay, model.AuditUnknownTeacher, model.AuditUnknownSubject, model.AuditIgnorePattern}
+)
+
+// auditLine is an audit entry with the German names of its action and entity.
+type auditLine struct {
+	model.AuditEntry
+	ActionText string
+	EntityText string
+}
+
+// auditOption is an option of a filter select.
+type auditOption struct {
+	Value    string
+	Text     string
+	Selected bool
+}
+
+func auditOptions(values []string, text func(string) string, selected string) []auditOption {
+	options := make([]auditOption, len(values))
+	for i, value := range values {
+		options[i] = auditOption{Value: value, Text: text(value), Selected: value == selected}
+	}
+	return options
+}
+
+// GetAudit serves the audit log. It is filtered by the parameters "actor",
+// "action", "entity", "key" and the days "from" and "to" (YYYY-MM-DD).
+func GetAudit(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	redirected, _ := ensureLoggedIn(w, r)
+	if redirected {
+		return
+	}
+
+	showAudit(w, r.URL.Query(), nil)
+}
+
+// showAudit is a helper function to show the audit log filtered by the form
+// values, see GetAudit.
+func showAudit(w http.ResponseWriter, form url.Values, data *generalTemplateData) {
+	filter := model.AuditFilter{Actor: form.Get("actor"), Action: form.Get("action"),
+		Entity: form.Get("entity"), Key: form.Get("key"), Limit: maxAuditEntries}
+	if from, err := time.ParseInLocation("2006-01-02", form.Get("from"), time.Local); err == nil {
+		filter.From = from
+	} else if form.Get("from") != "" {
+		data = simpleMessage("Das Datum "+form.Get("from")+" ist ungültig.", false)
+	}
+	if to, err := time.ParseInLocation("2006-01-02", form.Get("to"), time.Local); err == nil {
+		filter.To = to.AddDate(0, 0, 1)
+	} else if form.Get("to") != "" {
+		data = simpleMessage("Das Datum "+form.Get("to")+" ist ungültig.", false)
+	}
+
+	templateData := struct {
+		generalTemplateData
+		Lines    []auditLine
+		Limited  bool
+		Actors   []auditOption
+		Actions  []auditOption
+		Entities []auditOption
+		Key      string
+		From     string
+		To       string
+		// ExportURL exports the entries selected as CSV file.
+		ExportURL template.URL
+	}{
+		Actors:   auditOptions(model.ReadAuditActors(), func(actor string) string { return actor }, filter.Actor),
+		Actions:  auditOptions(auditActions, export.AuditActionText, filter.Action),
+		Entities: auditOptions(auditEntities, export.AuditEntityText, filter.Entity),
+		Key:      filter.Key,
+		From:     form.Get("from"),
+		To:       form.Get("to"),
+		ExportURL: template.URL("/export/audit?" + url.Values{"format": {"csv"}, "bom": {"1"}, "actor": {filter.Actor},
+			"action": {filter.Action}, "entity": {filter.Entity}, "key": {filter.Key},
+			"from": {form.Get("from")}, "to": {form.Get("to")}}.Encode()),
+	}
+	if data != nil {
+		templateData.generalTemplateData = *data
+	}
+	for _, entry := range model.ReadAudit(filter) {
+		templateData.Lines = append(templateData.Lines, auditLine{AuditEntry: entry,
+			ActionText: export.AuditActionText(entry.Action), EntityText: export.AuditEntityText(entry.Entity)})
+	}
+	templateData.Limited = len(templateData.Lines) == maxAuditEntries
+
+	template, err := template.ParseFiles("templates/base.html", "templates/audit/index.html")
+	if err != nil {
+		log.Printf("error: %v\n", err)
+	}
+
+	err = template.Execute(w, &templateData)
+	if err != nil {
+		log.Printf("error: %v\n", err)
+	}
+}
@@ -18,6 +18,8 @@ type generalTemplateData struct {
 type templateMessage struct {
 	Text     string
 	Positive bool
+	// Undo is the URL undoing the action the message is about, see storeUndo.
+	Undo string
 }
 
 func Index(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
@@ -109,6 +111,7 @@ func PostSignup(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
 
 	// Create new user
 	user.Create(password)
+	model.Audit(user.Name, model.AuditCreate, model.AuditUser, user.Name, nil, struct{ Name string }{user.Name})
 	// Login the user
 	login(w, user)
 	http.Redirect(w, r, "/", http.StatusSeeOther)
@@ -0,0 +1,131 @@
+package controller
+
+import (
+	"fmt"
+	"html"
+	"html/template"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/hkohlsaat/vtr/model"
+	"github.com/julienschmidt/httprouter"
+)
+
+// GetDisplay serves the full-screen plan for the signage displays. The layout is
+// taken from the display profile named by the "profile" parameter and may be
+// changed with the parameters "columns", "fontsize" and "duration".
+func GetDisplay(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	query := r.URL.Query()
+
+	profile := model.DefaultDisplayProfile
+	if name := query.Get("profile"); name != "" {
+		saved := model.DisplayProfile{Name: name}
+		if saved.Exists() {
+			saved.Read()
+			profile = saved
+		}
+	}
+	setPositive(&profile.Columns, query.Get("columns"))
+	setPositive(&profile.FontSize, query.Get("fontsize"))
+	setPositive(&profile.Duration, query.Get("duration"))
+
+	template, err := template.ParseFiles("templates/display/display.html")
+	if err != nil {
+		log.Printf("error: %v\n", err)
+	}
+
+	err = template.Execute(w, &profile)
+	if err != nil {
+		log.Printf("error: %v\n", err)
+	}
+}
+
+// setPositive sets n to the number in s if it is a positive number.
+func setPositive(n *int, s string) {
+	if value, err := strconv.Atoi(s); err == nil && value > 0 {
+		*n = value
+	}
+}
+
+// GetDisplayProfiles serves the list of all display profiles.
+func GetDisplayProfiles(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	redirected, _ := ensureLoggedIn(w, r)
+	if redirected {
+		return
+	}
+
+	showDisplayProfiles(w, r, nil)
+}
+
+// SaveDisplayProfile creates or updates a display profile and serves the list
+// of all display profiles.
+func SaveDisplayProfile(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	redirected, session := ensureLoggedIn(w, r)
+	if redirected {
+		return
+	}
+
+	r.ParseForm()
+	profile := model.DefaultDisplayProfile
+	profile.Name = html.EscapeString(r.Form.Get("name"))
+	setPositive(&profile.Columns, r.Form.Get("columns"))
+	setPositive(&profile.FontSize, r.Form.Get("fontsize"))
+	setPositive(&profile.Duration, r.Form.Get("duration"))
+
+	if len(profile.Name) == 0 {
+		showDisplayProfiles(w, r, simpleMessage("Der Name ist zu kurz.", false).Messages)
+		return
+	}
+
+	if profile.Exists() {
+		before := model.DisplayProfile{Name: profile.Name}
+		before.Read()
+		profile.Update()
+		model.Audit(session.Username, model.AuditUpdate, model.AuditDisplay, profile.Name, before, profile)
+	} else {
+		profile.Create()
+		model.Audit(session.Username, model.AuditCreate, model.AuditDisplay, profile.Name, nil, profile)
+	}
+
+	message := fmt.Sprintf("%s wurde gespeichert.", profile.Name)
+	showDisplayProfiles(w, r, simpleMessage(message, true).Messages)
+}
+
+// DeleteDisplayProfile deletes a display profile and serves the URL undoing it.
+func DeleteDisplayProfile(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+	redirected, session := ensureLoggedIn(w, r)
+	if redirected {
+		return
+	}
+
+	profile := model.DisplayProfile{Name: html.EscapeString(params.ByName("name"))}
+	if profile.Exists() {
+		profile.Read()
+		profile.Delete()
+		writeUndo(w, model.Audit(session.Username, model.AuditDelete, model.AuditDisplay, profile.Name, profile, nil))
+	} else {
+		http.NotFound(w, r)
+		return
+	}
+}
+
+// showDisplayProfiles is a helper function to show a list of all display profiles.
+func showDisplayProfiles(w http.ResponseWriter, r *http.Request, messages []templateMessage) {
+	templateData := struct {
+		generalTemplateData
+		Profiles []model.DisplayProfile
+		Default  model.DisplayProfile
+	}{Profiles: model.ReadAllDisplayProfiles(), Default: model.DefaultDisplayProfile}
+	templateData.Messages = messages
+
+	template, err := template.ParseFiles("templates/base.html", "templates/display/index.html")
+	if err != nil {
+		log.Printf("error: %v\n", err)
+	}
+
+	err = template.Execute(w, &templateData)
+	if err != nil {
+		log.Printf("error: %v\n", err)
+	}
+}
@@ -0,0 +1,168 @@
+package controller
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/hkohlsaat/vtr/model"
+	"github.com/julienschmidt/httprouter"
+)
+
+// heartbeatInterval is the interval comments are sent to the event stream
+// clients to keep connections through proxies open.
+const heartbeatInterval = 30 * time.Second
+
+// planEvent announces a new plan to the clients of the event stream.
+type planEvent struct {
+	ID      int64
+	changes []model.Change
+}
+
+// planEventSummary is the data sent with a plan event.
+type planEventSummary struct {
+	ID      int64
+	Added   int
+	Changed int
+	Removed int
+	// Classes are the classes affected by the changes.
+	Classes []string
+}
+
+// summary summarizes the changes of the event. If class isn't empty only
+// changes of this class are counted.
+func (event planEvent) summary(class string) planEventSummary {
+	summary := planEventSummary{ID: event.ID, Classes: []string{}}
+	seen := make(map[string]bool)
+	for _, change := range event.changes {
+		s := change.Substitution()
+		if class != "" && !s.HasClass(class) {
+			continue
+		}
+		switch change.Kind() {
+		case model.ChangeAdded:
+			summary.Added++
+		case model.ChangeChanged:
+			summary.Changed++
+		case model.ChangeRemoved:
+			summary.Removed++
+		}
+		for _, c := range s.Classes() {
+			if !seen[c] {
+				seen[c] = true
+				summary.Classes = append(summary.Classes, c)
+			}
+		}
+	}
+	model.SortClasses(summary.Classes)
+	return summary
+}
+
+// planEvents distributes plan events to all connected clients.
+var planEvents = struct {
+	sync.Mutex
+	clients map[chan planEvent]bool
+}{clients: make(map[chan planEvent]bool)}
+
+func subscribePlanEvents() chan planEvent {
+	events := make(chan planEvent, 4)
+	planEvents.Lock()
+	planEvents.clients[events] = true
+	planEvents.Unlock()
+	return events
+}
+
+func unsubscribePlanEvents(events chan planEvent) {
+	planEvents.Lock()
+	delete(planEvents.clients, events)
+	planEvents.Unlock()
+}
+
+// publishPlanEvent sends the event to all clients. Clients not keeping up
+// miss the event, they catch up with the next one.
+func publishPlanEvent(event planEvent) {
+	planEvents.Lock()
+	defer planEvents.Unlock()
+	for events := range planEvents.clients {
+		select {
+		case events <- event:
+		default:
+		}
+	}
+}
+
+// GetPlanEvents serves a stream of server-sent events announcing new plans. Every
+// event carries the plan's id and a summary of the changes. With the "class"
+// parameter only changes of that class are announced. Clients reconnecting with
+// the Last-Event-ID header get the changes they missed right away.
+func GetPlanEvents(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+	class := r.URL.Query().Get("class")
+
+	events := subscribePlanEvents()
+	defer unsubscribePlanEvents(events)
+
+	w.Header().Set("content-type", "text/event-stream; charset=utf-8")
+	w.Header().Set("cache-control", "no-cache")
+	w.Header().Set("x-accel-buffering", "no")
+	fmt.Fprintf(w, "retry: 10000\n\n")
+
+	if lastID, err := strconv.ParseInt(r.Header.Get("Last-Event-ID"), 10, 64); err == nil {
+		if event, ok := missedPlanEvent(lastID); ok {
+			writePlanEvent(w, event, class)
+		}
+	}
+	flusher.Flush()
+
+	heartbeat := time.NewTicker(heartbeatInterval)
+	defer heartbeat.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case event := <-events:
+			writePlanEvent(w, event, class)
+			flusher.Flush()
+		case <-heartbeat.C:
+			fmt.Fprintf(w, ": heartbeat\n\n")
+			flusher.Flush()
+		}
+	}
+}
+
+// missedPlanEvent returns the event for the newest plan if a client last saw
+// the plan with lastID. The changes are made against that plan if it still
+// exists.
+func missedPlanEvent(lastID int64) (planEvent, bool) {
+	last, ok := model.LastPlanUpload()
+	if !ok || last.ID <= lastID {
+		return planEvent{}, false
+	}
+	plan, err := last.Plan()
+	if err != nil {
+		return planEvent{}, false
+	}
+	seen := model.PlanUpload{ID: lastID}
+	old, err := seen.Plan()
+	if err != nil {
+		old = &model.Plan{}
+	}
+	return planEvent{ID: last.ID, changes: model.Compare(old, plan)}, true
+}
+
+// writePlanEvent writes the event unless it doesn't concern the class.
+func writePlanEvent(w http.ResponseWriter, event planEvent, class string) {
+	summary := event.summary(class)
+	if class != "" && summary.Added+summary.Changed+summary.Removed == 0 {
+		return
+	}
+	data, _ := json.Marshal(summary)
+	fmt.Fprintf(w, "id: %d\nevent: plan\ndata: %s\n\n", event.ID, data)
+}
@@ -0,0 +1,129 @@
+package controller
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/hkohlsaat/vtr/export"
+	"github.com/hkohlsaat/vtr/model"
+	"github.com/julienschmidt/httprouter"
+)
+
+// GetExport serves the form to export plans, teachers and subjects.
+func GetExport(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	redirected, _ := ensureLoggedIn(w, r)
+	if redirected {
+		return
+	}
+
+	templateData := struct {
+		generalTemplateData
+		Uploads []model.PlanUpload
+	}{Uploads: model.ReadAllPlanUploads()}
+
+	template, err := template.ParseFiles("templates/base.html", "templates/export/index.html")
+	if err != nil {
+		log.Printf("error: %v\n", err)
+	}
+
+	err = template.Execute(w, &templateData)
+	if err != nil {
+		log.Printf("error: %v\n", err)
+	}
+}
+
+// GetExportData serves a dataset as CSV or XLSX file or, for teachers and
+// subjects, as Untis export file. The parameters are "format" ("csv", "xlsx"
+// or "gpu"), for CSV "separator" (";", "," or "tab") and
+// "bom", and for plans either "plan" with the upload's id or "from" and "to"
+// with the first and last day (YYYY-MM-DD). The audit log is filtered by
+// "actor", "action", "entity" and "key" as well as "from" and "to".
+func GetExportData(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+	redirected, _ := ensureLoggedIn(w, r)
+	if redirected {
+		return
+	}
+
+	query := export.Query{Dataset: params.ByName("dataset")}
+	form := r.URL.Query()
+	var err error
+	if id := form.Get("plan"); id != "" {
+		query.PlanID, err = strconv.ParseInt(id, 10, 64)
+	}
+	if from := form.Get("from"); from != "" && err == nil {
+		query.From, err = time.Parse("2006-01-02", from)
+	}
+	if to := form.Get("to"); to != "" && err == nil {
+		query.To, err = time.Parse("2006-01-02", to)
+	}
+	if err != nil {
+		http.Error(w, "Ungültiger Plan oder ungültiges Datum.", http.StatusBadRequest)
+		return
+	}
+	query.Audit = model.AuditFilter{Actor: form.Get("actor"), Action: form.Get("action"),
+		Entity: form.Get("entity"), Key: form.Get("key")}
+
+	table, err := export.Read(query)
+	switch {
+	case err == export.ErrUnknownDataset || err == export.ErrNoPlan:
+		http.NotFound(w, r)
+		return
+	case err != nil:
+		log.Printf("error exporting %s: %v\n", query.Dataset, err)
+		http.Error(w, "Der Export ist fehlgeschlagen.", http.StatusInternalServerError)
+		return
+	}
+
+	var b bytes.Buffer
+	name := "vtr-" + query.Dataset
+	if !query.From.IsZero() {
+		name += "-" + query.From.Format("2006-01-02")
+	}
+	switch form.Get("format") {
+	case "xlsx":
+		err = export.WriteXLSX(&b, table)
+		name += ".xlsx"
+		w.Header().Set("content-type", "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet")
+	case "gpu":
+		switch query.Dataset {
+		case "teachers":
+			table, name = export.GPUTeachers(model.ReadAllTeachers()), "GPU004.TXT"
+		case "subjects":
+			table, name = export.GPUSubjects(model.ReadAllSubjects()), "GPU006.TXT"
+		default:
+			http.Error(w, "Nur Lehrer und Fächer können für Untis exportiert werden.", http.StatusBadRequest)
+			return
+		}
+		err = export.WriteGPU(&b, table)
+		w.Header().Set("content-type", "text/plain; charset=windows-1252")
+	case "", "csv":
+		options := export.CSVOptions{BOM: form.Get("bom") != ""}
+		switch form.Get("separator") {
+		case ",":
+			options.Separator = ','
+		case "tab":
+			options.Separator = '\t'
+		default:
+			options.Separator = ';'
+		}
+		err = export.WriteCSV(&b, table, options)
+		name += ".csv"
+		w.Header().Set("content-type", "text/csv; charset=utf-8")
+	default:
+		http.Error(w, "Unbekanntes Format.", http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		log.Printf("error exporting %s: %v\n", query.Dataset, err)
+		http.Error(w, "Der Export ist fehlgeschlagen.", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("content-disposition", fmt.Sprintf("attachment; filename=%q", name))
+	w.Write(b.Bytes())
+}
@@ -0,0 +1,201 @@
+package controller
+
+import (
+	"crypto/sha1"
+	"encoding/xml"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hkohlsaat/vtr/model"
+	"github.com/julienschmidt/httprouter"
+)
+
+const (
+	// feedUploads is the number of uploads whose changes are in the feeds.
+	feedUploads = 30
+	// feedEntries is the maximal number of entries of a feed.
+	feedEntries = 100
+)
+
+// atomFeed is an Atom feed (RFC 4287).
+type atomFeed struct {
+	XMLName xml.Name    `xml:"http://www.w3.org/2005/Atom feed"`
+	Title   string      `xml:"title"`
+	ID      string      `xml:"id"`
+	Updated string      `xml:"updated"`
+	Links   []atomLink  `xml:"link"`
+	Author  string      `xml:"author>name"`
+	Entries []atomEntry `xml:"entry"`
+}
+
+type atomLink struct {
+	Rel  string `xml:"rel,attr,omitempty"`
+	Href string `xml:"href,attr"`
+}
+
+type atomEntry struct {
+	Title   string   `xml:"title"`
+	ID      string   `xml:"id"`
+	Updated string   `xml:"updated"`
+	Link    atomLink `xml:"link"`
+	Content string   `xml:"content"`
+}
+
+// feedCache holds the history the feeds are made from. It is read again when
+// plans or teachers and subjects changed.
+var feedCache struct {
+	sync.Mutex
+	planVersion      int
+	directoryVersion int
+	valid            bool
+	history          []model.History
+}
+
+// GetFeed serves the changes of the last uploads as Atom feed.
+func GetFeed(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	serveFeed(w, r, "")
+}
+
+// GetClassFeed serves the changes of one class as Atom feed. The class may be
+// followed by ".atom".
+func GetClassFeed(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+	serveFeed(w, r, strings.TrimSuffix(params.ByName("class"), ".atom"))
+}
+
+// serveFeed serves the feed for all classes or only the given class. Every new
+// or changed substitution of an upload is an entry.
+func serveFeed(w http.ResponseWriter, r *http.Request, class string) {
+	base := baseURL(r)
+	feed := atomFeed{
+		Title:  "Vertretungsplan",
+		ID:     feedID("feed", class),
+		Author: "vtr",
+		Links: []atomLink{
+			{Rel: "self", Href: base + r.URL.Path},
+			{Rel: "alternate", Href: base + classPlanURL(class)},
+		},
+	}
+	if class != "" {
+		feed.Title += " " + class
+	}
+
+	for _, history := range readFeedHistory() {
+		for _, change := range history.Changes {
+			s := change.Substitution()
+			if change.Kind() == model.ChangeRemoved || class != "" && !s.HasClass(class) {
+				continue
+			}
+			if len(feed.Entries) == feedEntries {
+				break
+			}
+			feed.Entries = append(feed.Entries, feedEntry(history.Upload, change, base))
+		}
+	}
+	// Without any upload the feed is empty and up to date now.
+	updated := time.Now()
+	if last, ok := model.LastPlanUpload(); ok {
+		updated = last.Modified
+	}
+	feed.Updated = updated.UTC().Format(time.RFC3339)
+
+	w.Header().Set("content-type", "application/atom+xml; charset=utf-8")
+	w.Write([]byte(xml.Header))
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(feed); err != nil {
+		log.Printf("error writing feed: %v\n", err)
+	}
+}
+
+// readFeedHistory returns the changes of the last uploads.
+func readFeedHistory() []model.History {
+	planVersion, directoryVersion := model.PlanVersion(), model.DirectoryVersion()
+
+	feedCache.Lock()
+	defer feedCache.Unlock()
+	if !feedCache.valid || feedCache.planVersion != planVersion || feedCache.directoryVersion != directoryVersion {
+		feedCache.history = model.ReadHistory(feedUploads)
+		feedCache.planVersion, feedCache.directoryVersion = planVersion, directoryVersion
+		feedCache.valid = true
+	}
+	return feedCache.history
+}
+
+// feedEntry describes the change as feed entry. The entry's id is made from
+// the upload's time and the substitution's id, so it stays the same every
+// time the feed is made.
+func feedEntry(upload model.PlanUpload, change model.Change, base string) atomEntry {
+	s := change.After
+	title := "Neu"
+	if change.Kind() == model.ChangeChanged {
+		title = "Geändert"
+	}
+	title = fmt.Sprintf("%s: %s, %s %s, %s. Stunde", title, s.Class, weekdays[change.Day.Weekday()],
+		change.Day.Format("02.01."), s.Period)
+
+	content := describeSubstitution(*s)
+	if change.Kind() == model.ChangeChanged {
+		content += "\nVorher: " + describeSubstitution(*change.Before)
+	}
+
+	classes := s.Classes()
+	link := classPlanURL("")
+	if len(classes) == 1 {
+		link = classPlanURL(classes[0])
+	}
+
+	return atomEntry{
+		Title:   title,
+		ID:      feedID(upload.Time.UTC().Format(time.RFC3339Nano), s.ID),
+		Updated: upload.Time.UTC().Format(time.RFC3339),
+		Link:    atomLink{Href: base + link},
+		Content: content,
+	}
+}
+
+// describeSubstitution describes the substitution in one line, e.g.
+// "Vertretung: Herr Müller statt Frau Schmidt (Mathematik). Aufgaben".
+func describeSubstitution(s model.Substitution) string {
+	description := s.Kind
+	if description == "" {
+		description = "Vertretung"
+	}
+	description += ":"
+	if teacher := s.SubstTeacher.FullName(); teacher != "" {
+		description += " " + teacher
+	}
+	if teacher := s.InstdTeacher.FullName(); teacher != "" {
+		description += " statt " + teacher
+	}
+	if subject := printSubject(s.InstdSubject); subject != "" {
+		description += " (" + subject + ")"
+	}
+	description += "."
+	if s.Text != "" {
+		description += " " + s.Text
+	}
+	return description
+}
+
+// feedID makes an URN from the parts which is the same for the same parts.
+func feedID(parts ...string) string {
+	sum := sha1.Sum([]byte(strings.Join(parts, "\x00")))
+	sum[6] = sum[6]&0x0f | 0x50
+	sum[8] = sum[8]&0x3f | 0x80
+	return fmt.Sprintf("urn:uuid:%x-%x-%x-%x-%x", sum[0:4], sum[4:6], sum[6:8], sum[8:10], sum[10:16])
+}
+
+// baseURL returns the scheme and host the request was sent to.
+func baseURL(r *http.Request) string {
+	scheme := "http"
+	if r.TLS != nil || r.Header.Get("X-Forwarded-Proto") == "https" {
+		scheme = "https"
+	}
+	base := url.URL{Scheme: scheme, Host: r.Host}
+	return base.String()
+}
@@ -0,0 +1,233 @@
+package controller
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"html/template"
+	"io"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/hkohlsaat/vtr/importer"
+	"github.com/hkohlsaat/vtr/model"
+)
+
+// pendingImportDuration is the time an import can be applied after its preview.
+const pendingImportDuration = time.Hour
+
+// pendingImport is an import previewed but not applied yet.
+type pendingImport struct {
+	teachers []importer.TeacherRow
+	subjects []importer.SubjectRow
+	expires  time.Time
+}
+
+var pendingImports = struct {
+	sync.Mutex
+	imports map[string]pendingImport
+}{imports: make(map[string]pendingImport)}
+
+// storePendingImport keeps the import until it is applied and returns the
+// token to take it again.
+func storePendingImport(pending pendingImport) string {
+	token := randomToken()
+	pending.expires = time.Now().Add(pendingImportDuration)
+
+	pendingImports.Lock()
+	defer pendingImports.Unlock()
+	for t, p := range pendingImports.imports {
+		if p.expires.Before(time.Now()) {
+			delete(pendingImports.imports, t)
+		}
+	}
+	pendingImports.imports[token] = pending
+	return token
+}
+
+// randomToken returns a new token which can't be guessed.
+func randomToken() string {
+	b := make([]byte, 24)
+	if _, err := io.ReadFull(rand.Reader, b); err != nil {
+		log.Fatal(err)
+	}
+	return base64.URLEncoding.EncodeToString(b)
+}
+
+// takePendingImport returns the import stored with the token and removes it,
+// so it is only applied once.
+func takePendingImport(token string) (pendingImport, bool) {
+	pendingImports.Lock()
+	defer pendingImports.Unlock()
+	pending, ok := pendingImports.imports[token]
+	delete(pendingImports.imports, token)
+	return pending, ok && pending.expires.After(time.Now())
+}
+
+// importPreview lists the rows of an import by their status.
+type importPreview struct {
+	generalTemplateData
+	// Action is the URL the import is committed to.
+	Action string
+	Token  string
+	Lines  []importLine
+	// Kinds tells to show whether the rows are teachers or subjects.
+	Kinds bool
+	// Counts holds the number of rows of each status.
+	New, Changed, Unchanged, Duplicate, Invalid, Missing, Restored int
+}
+
+// importLine is a row of the import preview.
+type importLine struct {
+	// Kind is "Lehrer" or "Fach".
+	Kind    string
+	Line    int
+	Short   string
+	Name    string
+	Status  string
+	Message string
+	// Before is the name recorded until now for changed rows.
+	Before string
+}
+
+// StatusText returns the status in German.
+func (line importLine) StatusText() string {
+	switch line.Status {
+	case importer.StatusNew:
+		return "neu"
+	case importer.StatusChanged:
+		return "geändert"
+	case importer.StatusUnchanged:
+		return "unverändert"
+	case importer.StatusDuplicate:
+		return "doppelt"
+	case importer.StatusMissing:
+		return "wird inaktiv"
+	case importer.StatusRestored:
+		return "wird wiederhergestellt"
+	default:
+		return "ungültig"
+	}
+}
+
+func (preview *importPreview) add(line importLine) {
+	switch line.Status {
+	case importer.StatusNew:
+		preview.New++
+	case importer.StatusChanged:
+		preview.Changed++
+	case importer.StatusUnchanged:
+		preview.Unchanged++
+	case importer.StatusDuplicate:
+		preview.Duplicate++
+	case importer.StatusMissing:
+		preview.Missing++
+	case importer.StatusRestored:
+		preview.Restored++
+	default:
+		preview.Invalid++
+	}
+	preview.Lines = append(preview.Lines, line)
+}
+
+func (preview *importPreview) addTeachers(rows []importer.TeacherRow) {
+	for _, row := range rows {
+		line := importLine{Kind: "Lehrer", Line: row.Line, Short: row.Teacher.Short, Name: row.Teacher.FullName(), Status: row.Status, Message: row.Message}
+		if row.Status == importer.StatusChanged || row.Status == importer.StatusRestored && row.Existing.FullName() != line.Name {
+			line.Before = row.Existing.FullName()
+			if row.Existing.Inactive {
+				line.Before += " (inaktiv)"
+			}
+		}
+		preview.add(line)
+	}
+}
+
+func (preview *importPreview) addSubjects(rows []importer.SubjectRow) {
+	for _, row := range rows {
+		line := importLine{Kind: "Fach", Line: row.Line, Short: row.Subject.Short, Name: subjectDescription(row.Subject), Status: row.Status, Message: row.Message}
+		if row.Status == importer.StatusChanged || row.Status == importer.StatusRestored && subjectDescription(row.Existing) != line.Name {
+			line.Before = subjectDescription(row.Existing)
+		}
+		preview.add(line)
+	}
+}
+
+// auditTeacherRows records the changes made applying the rows for the actor.
+func auditTeacherRows(actor string, rows []importer.TeacherRow) {
+	for _, row := range rows {
+		if row.Applied == "" {
+			continue
+		}
+		var before interface{}
+		if row.Applied != model.AuditCreate {
+			before = row.Existing
+		}
+		after := model.Teacher{Short: row.Teacher.Short}
+		after.Read()
+		model.Audit(actor, row.Applied, model.AuditTeacher, after.Short, before, after)
+	}
+}
+
+// auditSubjectRows records the changes made applying the rows for the actor.
+func auditSubjectRows(actor string, rows []importer.SubjectRow) {
+	for _, row := range rows {
+		if row.Applied == "" {
+			continue
+		}
+		var before interface{}
+		if row.Applied != model.AuditCreate {
+			before = row.Existing
+		}
+		after := model.Subject{Short: row.Subject.Short}
+		after.Read()
+		model.Audit(actor, row.Applied, model.AuditSubject, after.Short, before, after)
+	}
+}
+
+// hasImportFile tells whether a file was uploaded in the form field.
+func hasImportFile(r *http.Request, field string) bool {
+	r.ParseMultipartForm(1 << 20)
+	_, _, err := r.FormFile(field)
+	return err == nil
+}
+
+// readImportFile reads the uploaded file of the form field. The error
+// message is meant for the user.
+func readImportFile(r *http.Request, field string) ([]byte, error) {
+	r.ParseMultipartForm(1 << 20)
+	file, _, err := r.FormFile(field)
+	if err != nil {
+		log.Printf("error receiving import file: %v\n", err)
+		return nil, errors.New("Es wurde keine Datei hochgeladen.")
+	}
+	defer file.Close()
+
+	data, err := ioutil.ReadAll(file)
+	if err != nil {
+		log.Printf("error reading import file: %v\n", err)
+		return nil, errors.New("Die Datei konnte nicht gelesen werden.")
+	}
+	return data, nil
+}
+
+// showImportPreview is a helper function to show the preview of an import.
+func showImportPreview(w http.ResponseWriter, title string, preview importPreview) {
+	templateData := struct {
+		importPreview
+		Title string
+	}{preview, title}
+
+	template, err := template.ParseFiles("templates/base.html", "templates/import/preview.html")
+	if err != nil {
+		log.Printf("error: %v\n", err)
+	}
+
+	err = template.Execute(w, &templateData)
+	if err != nil {
+		log.Printf("error: %v\n", err)
+	}
+}
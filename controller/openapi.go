@@ -0,0 +1,260 @@
+package controller
+
+import (
+	"net/http"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+)
+
+// apiVersion is the version of the API described by the OpenAPI document.
+const apiVersion = "1.0.0"
+
+// apiOperation describes an operation of the API for the OpenAPI document.
+type apiOperation struct {
+	Method  string
+	Path    string
+	ID      string
+	Summary string
+	Params  []apiParam
+	// Body is the request body, nil if there isn't any.
+	Body interface{}
+	// Status is the status of a successful response, 200 if not set. Response
+	// is nil for responses without content.
+	Status   int
+	Response interface{}
+	// Errors are the error statuses the operation may respond with.
+	Errors []int
+	// Token tells whether the operation needs a token.
+	Token bool
+}
+
+type apiParam struct {
+	Name        string
+	In          string
+	Type        string
+	Description string
+}
+
+var (
+	idParam = apiParam{Name: "id", In: "path", Type: "string",
+		Description: `Id of the plan or "latest" for the newest plan. Plans by id need a token.`}
+	shortParam      = apiParam{Name: "short", In: "path", Type: "string", Description: "Short of the teacher or subject."}
+	pageParam       = apiParam{Name: "page", In: "query", Type: "integer", Description: "Page to return, starting at 1."}
+	perPageParam    = apiParam{Name: "per_page", In: "query", Type: "integer", Description: "Items per page, at most 200."}
+	paginatedParams = []apiParam{pageParam, perPageParam}
+)
+
+// apiOperations are all operations of the API. Operations added to main.go
+// have to be added here, too.
+var apiOperations = []apiOperation{
+	{Method: "get", Path: "/plans", ID: "listPlans", Summary: "List the uploaded plans, the newest first.",
+		Params: paginatedParams, Response: apiPlanList{}, Errors: []int{400, 401}, Token: true},
+	{Method: "get", Path: "/plans/{id}", ID: "getPlan", Summary: "Get a plan with all its days.",
+		Params: []apiParam{idParam}, Response: apiPlan{}, Errors: []int{401, 404}},
+	{Method: "get", Path: "/plans/{id}/substitutions", ID: "listSubstitutions", Summary: "List the substitutions of a plan.",
+		Params: append([]apiParam{idParam,
+			{Name: "class", In: "query", Type: "string", Description: "Only list substitutions of this class."},
+			{Name: "date", In: "query", Type: "string", Description: "Only list substitutions of this day (YYYY-MM-DD)."},
+		}, paginatedParams...), Response: apiSubstitutionList{}, Errors: []int{400, 401, 404}},
+	{Method: "get", Path: "/teachers", ID: "listTeachers", Summary: "List all teachers.",
+		Params: paginatedParams, Response: apiTeacherList{}, Errors: []int{400, 401}, Token: true},
+	{Method: "get", Path: "/teachers/{short}", ID: "getTeacher", Summary: "Get a teacher.",
+		Params: []apiParam{shortParam}, Response: apiTeacher{}, Errors: []int{401, 404}, Token: true},
+	{Method: "get", Path: "/subjects", ID: "listSubjects", Summary: "List all subjects.",
+		Params: paginatedParams, Response: apiSubjectList{}, Errors: []int{400, 401}, Token: true},
+	{Method: "get", Path: "/subjects/{short}", ID: "getSubject", Summary: "Get a subject.",
+		Params: []apiParam{shortParam}, Response: apiSubject{}, Errors: []int{401, 404}, Token: true},
+
+	{Method: "post", Path: "/teachers", ID: "createTeacher", Summary: "Create a teacher.",
+		Body: apiTeacherInput{}, Status: 201, Response: apiTeacher{}, Errors: []int{400, 401, 409, 422}, Token: true},
+	{Method: "put", Path: "/teachers/{short}", ID: "updateTeacher", Summary: "Update a teacher or create it if it doesn't exist.",
+		Params: []apiParam{shortParam}, Body: apiTeacherInput{}, Response: apiTeacher{}, Errors: []int{400, 401, 404, 409, 422}, Token: true},
+	{Method: "delete", Path: "/teachers/{short}", ID: "deleteTeacher", Summary: "Move a teacher to the trash.",
+		Params: []apiParam{shortParam}, Status: 204, Errors: []int{401, 404}, Token: true},
+	{Method: "post", Path: "/subjects", ID: "createSubject", Summary: "Create a subject.",
+		Body: apiSubjectInput{}, Status: 201, Response: apiSubject{}, Errors: []int{400, 401, 409, 422}, Token: true},
+	{Method: "put", Path: "/subjects/{short}", ID: "updateSubject", Summary: "Update a subject or create it if it doesn't exist.",
+		Params: []apiParam{shortParam}, Body: apiSubjectInput{}, Response: apiSubject{}, Errors: []int{400, 401, 404, 409, 422}, Token: true},
+	{Method: "delete", Path: "/subjects/{short}", ID: "deleteSubject", Summary: "Move a subject to the trash.",
+		Params: []apiParam{shortParam}, Status: 204, Errors: []int{401, 404}, Token: true},
+	{Method: "get", Path: "/unknown/teachers", ID: "listUnknownTeachers", Summary: "List the shorts in plans which aren't known teachers.",
+		Response: apiUnknownList{}, Errors: []int{401}, Token: true},
+	{Method: "delete", Path: "/unknown/teachers/{short}", ID: "deleteUnknownTeacher", Summary: "Remove a short from the unknown teachers.",
+		Params: []apiParam{shortParam}, Status: 204, Errors: []int{401}, Token: true},
+	{Method: "get", Path: "/unknown/subjects", ID: "listUnknownSubjects", Summary: "List the shorts in plans which aren't known subjects.",
+		Response: apiUnknownList{}, Errors: []int{401}, Token: true},
+	{Method: "delete", Path: "/unknown/subjects/{short}", ID: "deleteUnknownSubject", Summary: "Remove a short from the unknown subjects.",
+		Params: []apiParam{shortParam}, Status: 204, Errors: []int{401}, Token: true},
+}
+
+// GetOpenAPI serves the OpenAPI document of the API.
+func GetOpenAPI(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	writeAPI(w, http.StatusOK, openAPIDocument())
+}
+
+// openAPIDocument generates the OpenAPI document from apiOperations. The
+// schemas are derived from the API types, so they can't get out of date.
+func openAPIDocument() map[string]interface{} {
+	schemas := make(map[string]interface{})
+	schemaOf(reflect.TypeOf(apiError{}), schemas)
+
+	paths := make(map[string]interface{})
+	for _, op := range apiOperations {
+		var parameters []interface{}
+		for _, param := range op.Params {
+			parameters = append(parameters, map[string]interface{}{
+				"name":        param.Name,
+				"in":          param.In,
+				"required":    param.In == "path",
+				"description": param.Description,
+				"schema":      map[string]interface{}{"type": param.Type},
+			})
+		}
+
+		status := op.Status
+		if status == 0 {
+			status = http.StatusOK
+		}
+		responses := map[string]interface{}{strconv.Itoa(status): map[string]interface{}{"description": http.StatusText(status)}}
+		if op.Response != nil {
+			responses[strconv.Itoa(status)] = response(http.StatusText(status), schemaOf(reflect.TypeOf(op.Response), schemas))
+		}
+		for _, status := range op.Errors {
+			responses[strconv.Itoa(status)] = response(http.StatusText(status), ref("Error"))
+		}
+
+		operation := map[string]interface{}{
+			"operationId": op.ID,
+			"summary":     op.Summary,
+			"responses":   responses,
+		}
+		if parameters != nil {
+			operation["parameters"] = parameters
+		}
+		if op.Body != nil {
+			operation["requestBody"] = map[string]interface{}{
+				"required": true,
+				"content": map[string]interface{}{
+					"application/json": map[string]interface{}{"schema": schemaOf(reflect.TypeOf(op.Body), schemas)},
+				},
+			}
+		}
+		if op.Token {
+			operation["security"] = []interface{}{map[string]interface{}{"token": []string{}}}
+		}
+
+		path, _ := paths[op.Path].(map[string]interface{})
+		if path == nil {
+			path = make(map[string]interface{})
+			paths[op.Path] = path
+		}
+		path[op.Method] = operation
+	}
+
+	return map[string]interface{}{
+		"openapi": "3.0.3",
+		"info": map[string]interface{}{
+			"title":   "vtr",
+			"version": apiVersion,
+		},
+		"servers": []interface{}{map[string]interface{}{"url": "/api/v1"}},
+		"paths":   paths,
+		"components": map[string]interface{}{
+			"schemas": schemas,
+			"securitySchemes": map[string]interface{}{
+				"token": map[string]interface{}{"type": "http", "scheme": "bearer"},
+			},
+		},
+	}
+}
+
+func response(description string, schema interface{}) map[string]interface{} {
+	return map[string]interface{}{
+		"description": description,
+		"content": map[string]interface{}{
+			"application/json": map[string]interface{}{"schema": schema},
+		},
+	}
+}
+
+func ref(name string) map[string]interface{} {
+	return map[string]interface{}{"$ref": "#/components/schemas/" + name}
+}
+
+// schemaOf returns the schema of the type. Structs are added to schemas under
+// their name without the "api" prefix and referenced.
+func schemaOf(t reflect.Type, schemas map[string]interface{}) map[string]interface{} {
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	switch {
+	case t == reflect.TypeOf(time.Time{}):
+		return map[string]interface{}{"type": "string", "format": "date-time"}
+	case t.Kind() == reflect.String:
+		return map[string]interface{}{"type": "string"}
+	case t.Kind() == reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case t.Kind() >= reflect.Int && t.Kind() <= reflect.Uint64:
+		return map[string]interface{}{"type": "integer"}
+	case t.Kind() == reflect.Slice:
+		return map[string]interface{}{"type": "array", "items": schemaOf(t.Elem(), schemas)}
+	case t.Kind() != reflect.Struct:
+		panic("openapi: unsupported type " + t.String())
+	}
+
+	name := strings.TrimPrefix(t.Name(), "api")
+	if _, ok := schemas[name]; !ok {
+		// Reserve the name first, so recursive types terminate.
+		schemas[name] = nil
+		properties := make(map[string]interface{})
+		var required []string
+		structFields(t, schemas, properties, &required)
+		sort.Strings(required)
+		schema := map[string]interface{}{"type": "object", "properties": properties}
+		if required != nil {
+			schema["required"] = required
+		}
+		schemas[name] = schema
+	}
+	return ref(name)
+}
+
+// structFields adds the fields of the struct to properties the way
+// encoding/json marshals them. Fields of embedded structs are added inline.
+func structFields(t reflect.Type, schemas, properties map[string]interface{}, required *[]string) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.Anonymous {
+			structFields(field.Type, schemas, properties, required)
+			continue
+		}
+
+		tag := strings.Split(field.Tag.Get("json"), ",")
+		name := tag[0]
+		if name == "-" || field.PkgPath != "" {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+
+		schema := schemaOf(field.Type, schemas)
+		if format := field.Tag.Get("format"); format != "" {
+			schema["format"] = format
+		}
+		if enum := field.Tag.Get("enum"); enum != "" {
+			schema["enum"] = strings.Split(enum, ",")
+		}
+		properties[name] = schema
+
+		if !(len(tag) > 1 && tag[1] == "omitempty") {
+			*required = append(*required, name)
+		}
+	}
+}
@@ -0,0 +1,130 @@
+package controller
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"io/ioutil"
+	"strings"
+	"testing"
+	"time"
+)
+
+var update = flag.Bool("update", false, "update the OpenAPI document in testdata")
+
+const openAPIFile = "testdata/openapi.json"
+
+// TestOpenAPIDocument fails when the API changes. If the change is intended,
+// run the test with -update and commit the new document.
+func TestOpenAPIDocument(t *testing.T) {
+	document, err := json.MarshalIndent(openAPIDocument(), "", "  ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	document = append(document, '\n')
+
+	if *update {
+		if err := ioutil.WriteFile(openAPIFile, document, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	expected, err := ioutil.ReadFile(openAPIFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(document, expected) {
+		t.Errorf("The OpenAPI document changed. Run the tests with -update if the change is intended.")
+	}
+}
+
+// TestAPIContract checks that the objects served conform to their schemas.
+func TestAPIContract(t *testing.T) {
+	teacher := apiTeacher{Short: "Md", Name: "Müller", Sex: "m", DisplayName: "Herr Müller"}
+	substitution := apiSubstitution{ID: "abc", Date: "2016-10-19", Period: "3", Classes: []string{"5a"},
+		Kind: "Vertretung", Text: "Aufgaben", SubstituteTeacher: &teacher, AbsentTeacher: &teacher,
+		Subject: &apiSubject{Short: "M", Name: "Mathematik"}, TaskProviders: []apiTeacher{teacher},
+		Rooms: []string{"A12"}, MovedTo: "Di 3. Std.", MovedFrom: "Mo 1. Std."}
+	info := apiPlanInfo{ID: 1, Uploaded: time.Now(), Confirmed: time.Now(), Hash: "ff"}
+
+	objects := map[string]interface{}{
+		"Plan":             apiPlan{apiPlanInfo: info, Created: time.Now(), Days: []apiDay{{Date: "2016-10-19", News: []string{}, Substitutions: []apiSubstitution{substitution}}}},
+		"PlanList":         apiPlanList{apiPagination{1, 50, 1}, []apiPlanInfo{info}},
+		"SubstitutionList": apiSubstitutionList{apiPagination{1, 50, 1}, []apiSubstitution{substitution}},
+		"TeacherList":      apiTeacherList{apiPagination{1, 50, 1}, []apiTeacher{teacher}},
+		"SubjectList":      apiSubjectList{apiPagination{1, 50, 0}, []apiSubject{}},
+		"UnknownList":      apiUnknownList{[]apiUnknown{{Short: "Xy"}}},
+		"Error":            apiError{apiErrorDetail{404, "not_found", "there is no plan"}},
+	}
+
+	document, _ := json.Marshal(openAPIDocument())
+	var spec struct {
+		Components struct {
+			Schemas map[string]map[string]interface{}
+		}
+	}
+	json.Unmarshal(document, &spec)
+
+	for name, object := range objects {
+		data, _ := json.Marshal(object)
+		var value interface{}
+		json.Unmarshal(data, &value)
+		for _, problem := range validate(value, spec.Components.Schemas[name], spec.Components.Schemas, name) {
+			t.Error(problem)
+		}
+	}
+}
+
+// validate returns the differences between the value and the schema.
+func validate(value interface{}, schema map[string]interface{}, schemas map[string]map[string]interface{}, path string) []string {
+	if ref, ok := schema["$ref"].(string); ok {
+		schema = schemas[strings.TrimPrefix(ref, "#/components/schemas/")]
+	}
+
+	var problems []string
+	switch schema["type"] {
+	case "object":
+		object, ok := value.(map[string]interface{})
+		if !ok {
+			return []string{path + ": not an object"}
+		}
+		properties, _ := schema["properties"].(map[string]interface{})
+		required, _ := schema["required"].([]interface{})
+		for _, name := range required {
+			if _, ok := object[name.(string)]; !ok {
+				problems = append(problems, path+"."+name.(string)+": missing")
+			}
+		}
+		for name, v := range object {
+			property, ok := properties[name].(map[string]interface{})
+			if !ok {
+				problems = append(problems, path+"."+name+": not in schema")
+				continue
+			}
+			problems = append(problems, validate(v, property, schemas, path+"."+name)...)
+		}
+	case "array":
+		array, ok := value.([]interface{})
+		if !ok {
+			return []string{path + ": not an array"}
+		}
+		for _, v := range array {
+			problems = append(problems, validate(v, schema["items"].(map[string]interface{}), schemas, path+"[]")...)
+		}
+	case "string":
+		if _, ok := value.(string); !ok {
+			problems = append(problems, path+": not a string")
+		}
+	case "integer":
+		if n, ok := value.(float64); !ok || n != float64(int64(n)) {
+			problems = append(problems, path+": not an integer")
+		}
+	case "boolean":
+		if _, ok := value.(bool); !ok {
+			problems = append(problems, path+": not a boolean")
+		}
+	default:
+		problems = append(problems, path+": unknown schema")
+	}
+	return problems
+}
@@ -1,14 +1,23 @@
 package controller
 
 import (
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"io/ioutil"
 	"log"
 	"mime/multipart"
 	"net/http"
 	"os"
+	"strconv"
 	"strings"
+	"sync"
+	"time"
 
+	"github.com/andybalholm/brotli"
 	"github.com/hkohlsaat/vtr/model"
 	"github.com/julienschmidt/httprouter"
 )
@@ -19,47 +28,107 @@ func PostPlan(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
 		http.Error(w, "falsches Passwort", http.StatusUnauthorized)
 		return
 	}
-	file, _, err := r.FormFile("vertretungsplan")
+	if r.MultipartForm == nil || len(r.MultipartForm.File["vertretungsplan"]) == 0 {
+		log.Printf("error recieving file from upload: %v\n", http.ErrMissingFile)
+		http.Error(w, "keine Datei", http.StatusBadRequest)
+		return
+	}
+
+	// Untis splits long plans into several files. They may be uploaded
+	// as multiple files or as one zip archive.
+	var pages [][]byte
+	for _, header := range r.MultipartForm.File["vertretungsplan"] {
+		upload, err := readUpload(header)
+		if err != nil {
+			log.Printf("error recieving file from upload: %v\n", err)
+			http.Error(w, "Datei nicht lesbar", http.StatusBadRequest)
+			return
+		}
+		filePages, err := model.UnpackPages(upload)
+		if err != nil {
+			log.Printf("error unpacking %s: %v\n", header.Filename, err)
+			http.Error(w, "Archiv nicht lesbar", http.StatusBadRequest)
+			return
+		}
+		pages = append(pages, filePages...)
+	}
+
+	result, err := processPlan(pages)
+	if err == errPlanNotSaved {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
 	if err != nil {
-		log.Printf("error recieving file from upload: %v\n", err)
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
 		return
 	}
-	go processPlan(file)
+	if result.Stored {
+		model.Audit(uploadActor, model.AuditUpload, model.AuditPlan, strconv.FormatInt(result.ID, 10), nil, result)
+	}
+	w.Header().Set("content-type", "application/json; charset=utf-8")
+	json.NewEncoder(w).Encode(result)
+}
+
+// uploadActor is recorded in the audit log for plan uploads. They are
+// authorized by the upload password, not by a user.
+const uploadActor = "Untis-Upload"
+
+// uploadResult tells the uploading client what happened to its upload.
+// Stored is false if the upload equals the newest plan and was not saved again.
+type uploadResult struct {
+	ID     int64
+	Stored bool
+	Hash   string
 }
 
-func processPlan(file multipart.File) {
+// errPlanNotSaved is returned by processPlan if the plan was read but couldn't
+// be saved. Other errors mean the plan couldn't be read.
+var errPlanNotSaved = errors.New("Der Plan konnte nicht gespeichert werden.")
+
+// readUpload reads the whole content of an uploaded file.
+func readUpload(header *multipart.FileHeader) ([]byte, error) {
+	file, err := header.Open()
+	if err != nil {
+		return nil, err
+	}
 	defer file.Close()
+	return ioutil.ReadAll(file)
+}
 
-	plan, err := model.ToPlan(file)
+func processPlan(pages [][]byte) (uploadResult, error) {
+	plan, err := model.ToPlanPages(pages)
 	if err != nil {
 		log.Printf("can't make an object of the plan: %v\n", err)
-		return
+		return uploadResult{}, err
 	}
 
-	file.Seek(0, 0)
-	bytes, _ := ioutil.ReadAll(file)
-	plan.Create(bytes)
+	file, err := model.PackPages(pages)
+	if err != nil {
+		log.Printf("can't pack the plan's pages: %v\n", err)
+		return uploadResult{}, err
+	}
+	previous := model.LastPlan()
+	if previous == nil {
+		previous = &model.Plan{}
+	}
 
-	for _, part := range plan.Parts {
-		for _, s := range part.Substitutions {
-			if !s.SubstTeacher.Exists() {
-				unknownTeacher := model.UnknownTeacher{Short: s.SubstTeacher.Short}
-				unknownTeacher.Create()
-			}
-			if !s.InstdTeacher.Exists() {
-				unknownTeacher := model.UnknownTeacher{Short: s.InstdTeacher.Short}
-				unknownTeacher.Create()
-			}
-			if !s.InstdSubject.Exists() {
-				unknownSubject := model.UnknownSubject{Short: s.InstdSubject.Short}
-				unknownSubject.Create()
-			}
-		}
+	result := uploadResult{Hash: plan.Hash()}
+	result.ID, result.Stored, err = plan.Create(file)
+	if err != nil {
+		log.Printf("error saving plan: %v\n", err)
+		return uploadResult{}, errPlanNotSaved
 	}
+	if !result.Stored {
+		return result, nil
+	}
+	publishPlanEvent(planEvent{ID: result.ID, changes: model.Compare(previous, plan)})
+
+	model.RecordUnknowns(plan, time.Now())
 
 	if len(os.Args) > 3 {
-		sentToFirebase()
+		go sentToFirebase()
 	}
+	return result, nil
 }
 
 func sentToFirebase() {
@@ -85,13 +154,138 @@ func sentToFirebase() {
 	}
 }
 
+// GetPlan serves the last plan in JSON format. Responses carry an ETag and a
+// Last-Modified header, so clients can ask whether the plan changed, and are
+// compressed with brotli or gzip if the client accepts it.
 func GetPlan(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
-	lastPlan := model.LastPlanJSON()
-	if lastPlan == "" {
+	cached, ok := cachedPlan()
+	if !ok {
 		http.NotFound(w, r)
 		return
-	} else {
-		w.Header().Set("content-type", "application/json; charset=utf-8")
-		fmt.Fprintln(w, lastPlan)
 	}
+
+	w.Header().Set("etag", cached.etag)
+	w.Header().Set("last-modified", cached.modified.UTC().Format(http.TimeFormat))
+	w.Header().Set("cache-control", "no-cache")
+	w.Header().Set("vary", "Accept-Encoding")
+	if notModified(r, cached.etag, cached.modified) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	encoding := acceptedEncoding(r.Header.Get("Accept-Encoding"))
+	body := cached.bodies[encoding]
+	if encoding != "" {
+		w.Header().Set("content-encoding", encoding)
+	}
+	w.Header().Set("content-type", "application/json; charset=utf-8")
+	w.Header().Set("content-length", strconv.Itoa(len(body)))
+	if r.Method != http.MethodHead {
+		w.Write(body)
+	}
+}
+
+// planResponse is the last plan prepared to be served.
+type planResponse struct {
+	etag     string
+	modified time.Time
+	// bodies holds the JSON by content encoding, "" being uncompressed.
+	bodies map[string][]byte
+}
+
+// planCache holds the response for the last plan. It is made again when plans
+// or teachers and subjects changed.
+var planCache struct {
+	sync.Mutex
+	planVersion      int
+	directoryVersion int
+	valid            bool
+	response         planResponse
+}
+
+// cachedPlan returns the response for the last plan. ok is false if there
+// isn't any plan.
+func cachedPlan() (response planResponse, ok bool) {
+	planVersion, directoryVersion := model.PlanVersion(), model.DirectoryVersion()
+
+	planCache.Lock()
+	defer planCache.Unlock()
+	if planCache.valid && planCache.planVersion == planVersion && planCache.directoryVersion == directoryVersion {
+		return planCache.response, true
+	}
+
+	upload, json := model.LastPlanJSON()
+	if json == "" {
+		return planResponse{}, false
+	}
+
+	body := []byte(json + "\n")
+	// The plan's hash changes with its substitutions, the hash of the body
+	// with the names of teachers and subjects.
+	sum := sha256.Sum256(body)
+	response = planResponse{
+		etag:     fmt.Sprintf("\"%.16s-%x\"", upload.Hash, sum[:4]),
+		modified: upload.Modified,
+		bodies:   map[string][]byte{"": body},
+	}
+	if changed := model.DirectoryChanged(); changed.After(response.modified) {
+		response.modified = changed
+	}
+
+	var gz bytes.Buffer
+	gzw, _ := gzip.NewWriterLevel(&gz, gzip.BestCompression)
+	gzw.Write(body)
+	gzw.Close()
+	response.bodies["gzip"] = gz.Bytes()
+
+	var br bytes.Buffer
+	brw := brotli.NewWriterLevel(&br, brotli.BestCompression)
+	brw.Write(body)
+	brw.Close()
+	response.bodies["br"] = br.Bytes()
+
+	planCache.planVersion, planCache.directoryVersion = planVersion, directoryVersion
+	planCache.valid = true
+	planCache.response = response
+	return response, true
+}
+
+// notModified tells whether the client's copy is still up to date. If-None-Match
+// takes precedence over If-Modified-Since.
+func notModified(r *http.Request, etag string, modified time.Time) bool {
+	if match := r.Header.Get("If-None-Match"); match != "" {
+		for _, tag := range strings.Split(match, ",") {
+			tag = strings.TrimPrefix(strings.TrimSpace(tag), "W/")
+			if tag == etag || tag == "*" {
+				return true
+			}
+		}
+		return false
+	}
+	since, err := http.ParseTime(r.Header.Get("If-Modified-Since"))
+	return err == nil && !modified.Truncate(time.Second).After(since)
+}
+
+// acceptedEncoding returns the best content encoding accepted by the client:
+// "br", "gzip" or "" for no compression.
+func acceptedEncoding(accept string) string {
+	accepted := make(map[string]bool)
+	for _, part := range strings.Split(accept, ",") {
+		fields := strings.Split(part, ";")
+		name := strings.ToLower(strings.TrimSpace(fields[0]))
+		quality := 1.0
+		for _, param := range fields[1:] {
+			if q := strings.TrimSpace(param); strings.HasPrefix(q, "q=") {
+				quality, _ = strconv.ParseFloat(q[2:], 64)
+			}
+		}
+		accepted[name] = quality > 0
+	}
+
+	for _, encoding := range []string{"br", "gzip"} {
+		if accepted[encoding] {
+			return encoding
+		}
+	}
+	return ""
 }
@@ -0,0 +1,276 @@
+package controller
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hkohlsaat/vtr/model"
+	"github.com/hkohlsaat/vtr/pdf"
+	"github.com/julienschmidt/httprouter"
+)
+
+// Layout of the printed plan in points.
+const (
+	printMargin     = 40.0
+	printFontSize   = 9.0
+	printLineHeight = 11.0
+	printPadding    = 3.0
+)
+
+// printGroup is a group of substitutions printed under one title, e.g. all
+// substitutions of a class.
+type printGroup struct {
+	Title         string
+	Substitutions []model.Substitution
+}
+
+// printColumn is a column of the printed table.
+type printColumn struct {
+	Title string
+	Width float64
+	Value func(s model.Substitution) string
+}
+
+var (
+	periodColumn  = printColumn{"Std.", 40, func(s model.Substitution) string { return s.Period }}
+	classColumn   = printColumn{"Klasse", 50, func(s model.Substitution) string { return s.Class }}
+	substColumn   = printColumn{"Vertretung", 100, func(s model.Substitution) string { return s.SubstTeacher.FullName() }}
+	insteadColumn = printColumn{"statt", 100, func(s model.Substitution) string { return s.InstdTeacher.FullName() }}
+	subjectColumn = printColumn{"Fach", 70, func(s model.Substitution) string { return printSubject(s.InstdSubject) }}
+	kindColumn    = printColumn{"Art", 65, func(s model.Substitution) string { return s.Kind }}
+	textColumn    = printColumn{"Hinweis", 0, func(s model.Substitution) string { return s.Text }}
+)
+
+// GetPlanPDF serves the last plan as PDF to be printed. The parameters "day"
+// (YYYY-MM-DD) and "class" restrict it to one day or class. With the parameter
+// "layout=teacher" the substitutions are grouped by substitute teacher instead
+// of by class.
+func GetPlanPDF(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	plan := model.LastPlan()
+	if plan == nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	query := r.URL.Query()
+	layout := query.Get("layout")
+	if layout != "" && layout != "class" && layout != "teacher" {
+		http.Error(w, `layout must be "class" or "teacher"`, http.StatusBadRequest)
+		return
+	}
+	class := query.Get("class")
+	if class != "" {
+		plan = plan.ForClass(class)
+	}
+
+	name := "vertretungsplan"
+	parts := plan.Parts
+	if day := query.Get("day"); day != "" {
+		parts = nil
+		for _, part := range plan.Parts {
+			if part.Day.Format("2006-01-02") == day {
+				parts = append(parts, part)
+			}
+		}
+		if parts == nil {
+			http.NotFound(w, r)
+			return
+		}
+		name += "-" + day
+	}
+	if class != "" {
+		name += "-" + class
+	}
+
+	document := printPlan(plan.Created, parts, class, layout == "teacher")
+	w.Header().Set("content-type", "application/pdf")
+	w.Header().Set("content-disposition", fmt.Sprintf("inline; filename=%q", name+".pdf"))
+	if _, err := document.WriteTo(w); err != nil {
+		log.Printf("error writing plan pdf: %v\n", err)
+	}
+}
+
+// printPlan draws every day on its own pages.
+func printPlan(created time.Time, parts []model.Part, class string, byTeacher bool) *pdf.Document {
+	document := pdf.New()
+	document.Title = "Vertretungsplan"
+
+	// The class or the substitute teacher is the title of the groups.
+	columns := []printColumn{periodColumn, substColumn, insteadColumn, subjectColumn, kindColumn, textColumn}
+	if byTeacher {
+		columns = []printColumn{periodColumn, classColumn, insteadColumn, subjectColumn, kindColumn, textColumn}
+	}
+	// The last column takes the remaining width.
+	width := document.Width - 2*printMargin
+	for _, column := range columns[:len(columns)-1] {
+		width -= column.Width
+	}
+	columns[len(columns)-1].Width = width
+
+	for _, part := range parts {
+		title := fmt.Sprintf("Vertretungsplan %s, %s", weekdays[part.Day.Weekday()], part.Day.Format("02.01.2006"))
+		if class != "" {
+			title += " – Klasse " + class
+		}
+		printDay(document, title, created, part.News, groupForPrint(part, class, byTeacher), columns)
+	}
+
+	// Number the pages.
+	for i := 0; i < document.PageCount(); i++ {
+		document.SetPage(i)
+		footer := fmt.Sprintf("Seite %d von %d", i+1, document.PageCount())
+		document.TextRight(document.Width-printMargin, document.Height-printMargin/2, pdf.Helvetica, 8, footer)
+	}
+	return document
+}
+
+// printDay draws the news and the substitutions of one day starting on a new
+// page. The table continues on further pages if needed.
+func printDay(document *pdf.Document, title string, created time.Time, news []string, groups []printGroup, columns []printColumn) {
+	var y float64
+	bottom := document.Height - printMargin
+	newPage := func() {
+		document.AddPage()
+		document.Text(printMargin, printMargin, pdf.HelveticaBold, 14, title)
+		document.TextRight(document.Width-printMargin, printMargin, pdf.Helvetica, 8,
+			"Stand: "+created.Format("02.01.2006 15:04"))
+		document.Line(printMargin, printMargin+5, document.Width-printMargin, printMargin+5, 0.5)
+		y = printMargin + 20
+	}
+	header := func() {
+		x := printMargin
+		for _, column := range columns {
+			document.Text(x+printPadding, y+printFontSize, pdf.HelveticaBold, printFontSize, column.Title)
+			x += column.Width
+		}
+		y += printLineHeight + printPadding
+		document.Line(printMargin, y, document.Width-printMargin, y, 0.5)
+	}
+
+	newPage()
+	for _, line := range news {
+		for _, wrapped := range pdf.Wrap(pdf.Helvetica, 10, line, document.Width-2*printMargin) {
+			document.Text(printMargin, y+10, pdf.Helvetica, 10, wrapped)
+			y += 13
+		}
+	}
+	if len(news) > 0 {
+		y += 8
+	}
+
+	if len(groups) == 0 {
+		document.Text(printMargin, y+10, pdf.Helvetica, 10, "Keine Vertretungen.")
+		return
+	}
+
+	header()
+	for _, group := range groups {
+		// Keep the group title together with its first row.
+		if y+2*printLineHeight+3*printPadding > bottom {
+			newPage()
+			header()
+		}
+		document.Rect(printMargin, y, document.Width-2*printMargin, printLineHeight+printPadding, 0.9)
+		document.Text(printMargin+printPadding, y+printFontSize+1, pdf.HelveticaBold, printFontSize, group.Title)
+		y += printLineHeight + printPadding
+
+		for _, s := range group.Substitutions {
+			cells := make([][]string, len(columns))
+			lines := 1
+			for i, column := range columns {
+				cells[i] = pdf.Wrap(pdf.Helvetica, printFontSize, column.Value(s), column.Width-2*printPadding)
+				if len(cells[i]) > lines {
+					lines = len(cells[i])
+				}
+			}
+			height := float64(lines)*printLineHeight + printPadding
+			if y+height > bottom {
+				newPage()
+				header()
+			}
+
+			x := printMargin
+			for i, column := range columns {
+				for j, line := range cells[i] {
+					document.Text(x+printPadding, y+printFontSize+1+float64(j)*printLineHeight, pdf.Helvetica, printFontSize, line)
+				}
+				x += column.Width
+			}
+			y += height
+			document.Line(printMargin, y, document.Width-printMargin, y, 0.2)
+		}
+	}
+}
+
+// groupForPrint groups the substitutions of the day by class, only the given
+// one if any, or by substitute teacher. Teachers are sorted by name, their
+// substitutions by period.
+func groupForPrint(part model.Part, class string, byTeacher bool) []printGroup {
+	var groups []printGroup
+	if !byTeacher {
+		for _, day := range groupByClass(&model.Plan{Parts: []model.Part{part}}, class) {
+			for _, class := range day.Classes {
+				groups = append(groups, printGroup{Title: class.Class, Substitutions: class.Substitutions})
+			}
+		}
+		return groups
+	}
+
+	index := make(map[string]int)
+	for _, s := range part.Substitutions {
+		title := "Ohne Vertretung"
+		if s.SubstTeacher.Short != "" {
+			title = s.SubstTeacher.FullName()
+		}
+		i, ok := index[title]
+		if !ok {
+			i = len(groups)
+			index[title] = i
+			groups = append(groups, printGroup{Title: title})
+		}
+		groups[i].Substitutions = append(groups[i].Substitutions, s)
+	}
+
+	sort.SliceStable(groups, func(i, j int) bool {
+		return printSortName(groups[i].Title) < printSortName(groups[j].Title)
+	})
+	for _, group := range groups {
+		sort.SliceStable(group.Substitutions, func(i, j int) bool {
+			return firstPeriod(group.Substitutions[i].Period) < firstPeriod(group.Substitutions[j].Period)
+		})
+	}
+	return groups
+}
+
+// printSortName sorts teachers by name without compellation, and the group
+// without substitute teacher last.
+func printSortName(title string) string {
+	if title == "Ohne Vertretung" {
+		return "\uffff"
+	}
+	title = strings.TrimPrefix(title, "Herr ")
+	title = strings.TrimPrefix(title, "Frau ")
+	return strings.ToLower(title)
+}
+
+// firstPeriod returns the first period of e.g. "3 - 4".
+func firstPeriod(period string) int {
+	end := strings.IndexFunc(period, func(r rune) bool { return r < '0' || r > '9' })
+	if end == -1 {
+		end = len(period)
+	}
+	n, _ := strconv.Atoi(period[:end])
+	return n
+}
+
+func printSubject(subject model.Subject) string {
+	if subject.Name != "" {
+		return subject.Name
+	}
+	return subject.Short
+}
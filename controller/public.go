@@ -0,0 +1,133 @@
+package controller
+
+import (
+	"html/template"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/hkohlsaat/vtr/model"
+	"github.com/julienschmidt/httprouter"
+)
+
+// classCookieName is the name of the cookie remembering the class picked
+// on the public plan.
+const classCookieName = "vtr_klasse"
+
+var weekdays = [...]string{"Sonntag", "Montag", "Dienstag", "Mittwoch", "Donnerstag", "Freitag", "Samstag"}
+
+// publicDay is one day of the public plan with the substitutions grouped by class.
+type publicDay struct {
+	Day     time.Time
+	Weekday string
+	Classes []publicClass
+}
+
+type publicClass struct {
+	Class         string
+	Substitutions []model.Substitution
+}
+
+// GetPublicPlan serves the current plan to students and parents. A class picked
+// with the "klasse" parameter is remembered in a cookie.
+func GetPublicPlan(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	if classes, ok := r.URL.Query()["klasse"]; ok {
+		class := classes[0]
+		cookie := http.Cookie{Name: classCookieName, Value: url.QueryEscape(class), Path: "/", MaxAge: 365 * 24 * 3600}
+		if class == "" {
+			cookie.MaxAge = -1
+		}
+		http.SetCookie(w, &cookie)
+		http.Redirect(w, r, classPlanURL(class), http.StatusSeeOther)
+		return
+	}
+
+	if cookie, err := r.Cookie(classCookieName); err == nil {
+		if class, _ := url.QueryUnescape(cookie.Value); class != "" {
+			http.Redirect(w, r, classPlanURL(class), http.StatusSeeOther)
+			return
+		}
+	}
+
+	showPublicPlan(w, r, "")
+}
+
+// GetPublicClassPlan serves the current plan of one class.
+func GetPublicClassPlan(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+	showPublicPlan(w, r, params.ByName("class"))
+}
+
+// classPlanURL returns the URL of the class' public plan.
+func classPlanURL(class string) string {
+	if class == "" {
+		return "/vertretungsplan"
+	}
+	return "/vertretungsplan/" + url.PathEscape(class)
+}
+
+// showPublicPlan is a helper function to show the current plan for all classes
+// or only the given class.
+func showPublicPlan(w http.ResponseWriter, r *http.Request, class string) {
+	templateData := struct {
+		Created time.Time
+		Class   string
+		Classes []string
+		Days    []publicDay
+	}{Class: class}
+
+	if plan := model.LastPlan(); plan != nil {
+		templateData.Created = plan.Created
+		templateData.Classes = plan.Classes()
+		if class != "" {
+			plan = plan.ForClass(class)
+		}
+		templateData.Days = groupByClass(plan, class)
+	}
+
+	template, err := template.ParseFiles("templates/public/base.html", "templates/public/plan.html")
+	if err != nil {
+		log.Printf("error: %v\n", err)
+	}
+
+	err = template.Execute(w, &templateData)
+	if err != nil {
+		log.Printf("error: %v\n", err)
+	}
+}
+
+// groupByClass groups the substitutions of each day of the plan by class. A
+// substitution for several classes, e.g. "5a, 5b", is listed for each of them
+// like in the class picker, unless only the given class is shown.
+func groupByClass(plan *model.Plan, only string) []publicDay {
+	days := make([]publicDay, 0, len(plan.Parts))
+	for _, part := range plan.Parts {
+		day := publicDay{Day: part.Day, Weekday: weekdays[part.Day.Weekday()]}
+
+		groups := make(map[string][]model.Substitution)
+		var classes []string
+		for _, s := range part.Substitutions {
+			sClasses := s.Classes()
+			if len(sClasses) == 0 {
+				sClasses = []string{s.Class}
+			}
+			for _, class := range sClasses {
+				if only != "" && !strings.EqualFold(class, only) {
+					continue
+				}
+				if _, ok := groups[class]; !ok {
+					classes = append(classes, class)
+				}
+				groups[class] = append(groups[class], s)
+			}
+		}
+		model.SortClasses(classes)
+		for _, class := range classes {
+			day.Classes = append(day.Classes, publicClass{Class: class, Substitutions: groups[class]})
+		}
+
+		days = append(days, day)
+	}
+	return days
+}
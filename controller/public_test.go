@@ -0,0 +1,42 @@
+package controller
+
+import (
+	"bytes"
+	"html/template"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hkohlsaat/vtr/model"
+)
+
+func TestPublicPlanClasses(t *testing.T) {
+	day := time.Date(2016, 10, 21, 0, 0, 0, 0, time.UTC)
+	plan := &model.Plan{Created: day, Parts: []model.Part{{Day: day, Substitutions: []model.Substitution{
+		{Class: "5a, 5b", Period: "3", Kind: "Entfall"}}}}}
+	data := struct {
+		Created time.Time
+		Class   string
+		Classes []string
+		Days    []publicDay
+	}{Created: day, Classes: plan.Classes(), Days: groupByClass(plan, "")}
+
+	template, err := template.ParseFiles("../templates/public/base.html", "../templates/public/plan.html")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var page bytes.Buffer
+	if err := template.Execute(&page, &data); err != nil {
+		t.Fatal(err)
+	}
+
+	// The substitution is listed in each class' group with the group's class.
+	for _, class := range []string{"5a", "5b"} {
+		if !strings.Contains(page.String(), `<td class="class">`+class+`</td>`) {
+			t.Errorf("Group of class %s is missing.", class)
+		}
+	}
+	if strings.Contains(page.String(), `<td class="class">5a, 5b</td>`) {
+		t.Error("Substitution is shown with all its classes.")
+	}
+}
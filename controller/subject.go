@@ -1,14 +1,14 @@
 package controller
 
 import (
-	"encoding/json"
 	"fmt"
 	"html"
 	"html/template"
-	"io/ioutil"
 	"log"
 	"net/http"
+	"time"
 
+	"github.com/hkohlsaat/vtr/importer"
 	"github.com/hkohlsaat/vtr/model"
 	"github.com/julienschmidt/httprouter"
 )
@@ -49,7 +49,7 @@ func NewSubject(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
 
 // CreateSubject creates a new subject and serves the list of all subjects.
 func CreateSubject(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
-	redirected, _ := ensureLoggedIn(w, r)
+	redirected, session := ensureLoggedIn(w, r)
 	if redirected {
 		return
 	}
@@ -61,6 +61,9 @@ func CreateSubject(w http.ResponseWriter, r *http.Request, _ httprouter.Params)
 		subject := model.Subject{Short: short}
 		if subject.Exists() {
 			message = fmt.Sprintf("Es gibt bereits ein Fach mit dem Kürzel %s.", short)
+			if subject.Read(); !subject.ArchivedFrom.IsZero() {
+				message = fmt.Sprintf("Es gibt bereits ein Fach mit dem Kürzel %s im Papierkorb.", short)
+			}
 			valid = false
 		}
 	}
@@ -82,6 +85,7 @@ func CreateSubject(w http.ResponseWriter, r *http.Request, _ httprouter.Params)
 	// Create the subject.
 	subject := model.Subject{Short: short, Name: name, SplitClass: splitClass}
 	subject.Create()
+	model.Audit(session.Username, model.AuditCreate, model.AuditSubject, subject.Short, nil, subject)
 
 	unknown := model.UnknownSubject{Short: short}
 	unknown.Delete()
@@ -122,62 +126,80 @@ func showSubjects(w http.ResponseWriter, r *http.Request, message string) {
 	}
 }
 
-// NewSubjects serves the upload form to submit multiple subject records.
+// NewSubjects serves the upload form to import multiple subject records.
 func NewSubjects(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
-	template, err := template.ParseFiles("templates/base.html", "templates/subject/upload.html")
-	if err != nil {
-		log.Printf("error: %v\n", err)
+	redirected, _ := ensureLoggedIn(w, r)
+	if redirected {
+		return
 	}
 
-	err = template.Execute(w, nil)
-	if err != nil {
-		log.Printf("error: %v\n", err)
-	}
+	showSubjectUpload(w, r, nil)
 }
 
-// CreateSubjects creates multiple subjects from json upload and serves the list
-// of all subjects.
+// CreateSubjects reads the subjects of an uploaded CSV or JSON file and serves
+// a preview of the import. The import is applied by CommitSubjects.
 func CreateSubjects(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
 	redirected, _ := ensureLoggedIn(w, r)
 	if redirected {
 		return
 	}
 
-	r.ParseMultipartForm(16384)
-	file, _, err := r.FormFile("subjectjson")
+	data, err := readImportFile(r, "subjectjson")
 	if err != nil {
-		log.Printf("error recieving subject file from upload: %v\n", err)
+		showSubjectUpload(w, r, simpleMessage(err.Error(), false))
 		return
 	}
-	defer file.Close()
-
-	bytes, err := ioutil.ReadAll(file)
+	rows, err := importer.ReadSubjects(data)
 	if err != nil {
 		log.Printf("error reading subject file: %v\n", err)
+		showSubjectUpload(w, r, simpleMessage("Die Datei konnte nicht gelesen werden: "+err.Error(), false))
 		return
 	}
 
-	type tmpSubject struct {
-		model.Subject
-		ConcurrentlyTaught bool
+	preview := importPreview{Action: "/subjects/upload/commit", Token: storePendingImport(pendingImport{subjects: rows})}
+	preview.addSubjects(rows)
+	showImportPreview(w, "Fächer importieren", preview)
+}
+
+// CommitSubjects applies a previewed subject import and serves the list of all
+// subjects with a summary.
+func CommitSubjects(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	redirected, session := ensureLoggedIn(w, r)
+	if redirected {
+		return
 	}
-	subjects := []tmpSubject{}
-	err = json.Unmarshal(bytes, &subjects)
-	if err != nil {
-		log.Printf("error unmarshaling subject data: %v\n", err)
+
+	r.ParseForm()
+	pending, ok := takePendingImport(r.Form.Get("token"))
+	if !ok {
+		showSubjectUpload(w, r, simpleMessage("Der Import ist abgelaufen. Bitte die Datei erneut hochladen.", false))
 		return
 	}
-	for _, subject := range subjects {
-		if subject.ConcurrentlyTaught {
-			subject.SplitClass = true
-		}
-		subject.Create()
 
-		unknown := model.UnknownSubject{Short: subject.Short}
-		unknown.Delete()
+	summary := importer.ApplySubjects(pending.subjects, r.Form.Get("update") != "")
+	auditSubjectRows(session.Username, pending.subjects)
+	showSubjects(w, r, "Import abgeschlossen: "+summary.String()+".")
+}
+
+// showSubjectUpload is a helper function to show the upload form.
+func showSubjectUpload(w http.ResponseWriter, r *http.Request, data *generalTemplateData) {
+	template, err := template.ParseFiles("templates/base.html", "templates/subject/upload.html")
+	if err != nil {
+		log.Printf("error: %v\n", err)
+	}
+
+	err = template.Execute(w, data)
+	if err != nil {
+		log.Printf("error: %v\n", err)
 	}
+}
 
-	http.Redirect(w, r, "/subjects", http.StatusSeeOther)
+// subjectDescription describes the subject in the import preview.
+func subjectDescription(subject model.Subject) string {
+	if subject.SplitClass {
+		return subject.Name + " (geteilte Klasse)"
+	}
+	return subject.Name
 }
 
 // GetSubject serves one subject.
@@ -227,7 +249,12 @@ func EditSubject(w http.ResponseWriter, r *http.Request, params httprouter.Param
 	}
 	subject.Read()
 
-	// Execute template with subject as template data.
+	showSubjectEdit(w, subject, nil)
+}
+
+// showSubjectEdit is a helper function to show the form to edit a subject together
+// with the subject's aliases and former shorts.
+func showSubjectEdit(w http.ResponseWriter, subject model.Subject, data *generalTemplateData) {
 	template, err := template.ParseFiles("templates/base.html", "templates/subject/edit.html")
 	if err != nil {
 		log.Printf("error: %v\n", err)
@@ -236,8 +263,15 @@ func EditSubject(w http.ResponseWriter, r *http.Request, params httprouter.Param
 	templateData := struct {
 		generalTemplateData
 		model.Subject
+		Aliases []model.Alias
+		Renames []model.Rename
 	}{}
+	if data != nil {
+		templateData.generalTemplateData = *data
+	}
 	templateData.Subject = subject
+	templateData.Aliases = model.ReadAliases(model.AliasSubject, subject.Short)
+	templateData.Renames = model.ReadRenames(model.AliasSubject, subject.Short)
 
 	err = template.Execute(w, &templateData)
 	if err != nil {
@@ -247,7 +281,7 @@ func EditSubject(w http.ResponseWriter, r *http.Request, params httprouter.Param
 
 // UpdateSubject updates a subject with the uploaded information.
 func UpdateSubject(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
-	redirected, _ := ensureLoggedIn(w, r)
+	redirected, session := ensureLoggedIn(w, r)
 	if redirected {
 		return
 	}
@@ -258,10 +292,11 @@ func UpdateSubject(w http.ResponseWriter, r *http.Request, params httprouter.Par
 		http.NotFound(w, r)
 		return
 	}
+	subject.Read()
 
 	// Parse and validate subject data.
 	nshort, name, splitClass := parseSubjectData(r)
-	valid, message := validateSubjectData(short, name, splitClass)
+	valid, message := validateSubjectData(nshort, name, splitClass)
 	if !valid {
 		http.Error(w, message, http.StatusNotAcceptable)
 		return
@@ -270,13 +305,23 @@ func UpdateSubject(w http.ResponseWriter, r *http.Request, params httprouter.Par
 	// Update subject and send the new URL back to the client.
 	// It might have changed with an update of short.
 	updSubject := model.Subject{Short: nshort, Name: name, SplitClass: splitClass}
-	updSubject.UpdateShort(short)
+	if err := updSubject.UpdateShort(short); err == model.ErrShortTaken {
+		http.Error(w, fmt.Sprintf("Das Kürzel %s ist schon vergeben.", nshort), http.StatusConflict)
+		return
+	} else if err != nil {
+		log.Printf("error renaming subject %s: %v\n", short, err)
+		http.Error(w, "Das Fach konnte nicht gespeichert werden.", http.StatusInternalServerError)
+		return
+	}
+	updSubject.Read()
+	model.Audit(session.Username, model.AuditUpdate, model.AuditSubject, short, subject, updSubject)
 	w.Write([]byte(fmt.Sprintf("/subject/%s", nshort)))
 }
 
-// DeleteSubject deletes a subject and serves nothing (empty 200 OK response).
+// DeleteSubject moves a subject to the trash and serves the URL undoing it, see
+// writeUndo.
 func DeleteSubject(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
-	redirected, _ := ensureLoggedIn(w, r)
+	redirected, session := ensureLoggedIn(w, r)
 	if redirected {
 		return
 	}
@@ -284,7 +329,10 @@ func DeleteSubject(w http.ResponseWriter, r *http.Request, params httprouter.Par
 	short := html.EscapeString(params.ByName("short"))
 	subject := model.Subject{Short: short}
 	if subject.Exists() {
-		subject.Delete()
+		subject.Read()
+		before := subject
+		subject.Archive(time.Now(), time.Time{})
+		writeUndo(w, model.Audit(session.Username, model.AuditArchive, model.AuditSubject, short, before, subject))
 	} else {
 		http.NotFound(w, r)
 		return
@@ -302,6 +350,33 @@ func parseSubjectData(r *http.Request) (short, name string, splitClass bool) {
 	return
 }
 
+// ArchiveSubject moves a subject to the trash for the time range sent in the form
+// and serves the form to edit the subject again.
+func ArchiveSubject(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+	redirected, session := ensureLoggedIn(w, r)
+	if redirected {
+		return
+	}
+
+	subject := model.Subject{Short: params.ByName("short")}
+	if !subject.Exists() {
+		http.NotFound(w, r)
+		return
+	}
+	subject.Read()
+
+	r.ParseForm()
+	from, until, message := parseArchiveRange(r.Form.Get("from"), r.Form.Get("until"))
+	if message != "" {
+		showSubjectEdit(w, subject, simpleMessage(message, false))
+		return
+	}
+	before := subject
+	subject.Archive(from, until)
+	id := model.Audit(session.Username, model.AuditArchive, model.AuditSubject, subject.Short, before, subject)
+	showSubjectEdit(w, subject, undoMessage(archiveMessage(subject.Short, from, until), id))
+}
+
 func validateSubjectData(short, name string, splitClass bool) (valid bool, message string) {
 	if len(short) == 0 {
 		return false, "Das Kürzel ist zu kurz."
@@ -1,14 +1,14 @@
 package controller
 
 import (
-	"encoding/json"
 	"fmt"
 	"html"
 	"html/template"
-	"io/ioutil"
 	"log"
 	"net/http"
+	"time"
 
+	"github.com/hkohlsaat/vtr/importer"
 	"github.com/hkohlsaat/vtr/model"
 	"github.com/julienschmidt/httprouter"
 )
@@ -49,7 +49,7 @@ func NewTeacher(w http.ResponseWriter, r *http.Request, params httprouter.Params
 
 // CreateTeacher creates a new teacher and serves the list of all teachers.
 func CreateTeacher(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
-	redirected, _ := ensureLoggedIn(w, r)
+	redirected, session := ensureLoggedIn(w, r)
 	if redirected {
 		return
 	}
@@ -65,6 +65,9 @@ func CreateTeacher(w http.ResponseWriter, r *http.Request, _ httprouter.Params)
 		teacher := model.Teacher{Short: short}
 		if teacher.Exists() {
 			message = fmt.Sprintf("Es gibt bereits einen Lehrer mit dem Kürzel %s.", short)
+			if teacher.Read(); !teacher.ArchivedFrom.IsZero() {
+				message = fmt.Sprintf("Es gibt bereits einen Lehrer mit dem Kürzel %s im Papierkorb.", short)
+			}
 			valid = false
 		}
 	}
@@ -86,6 +89,7 @@ func CreateTeacher(w http.ResponseWriter, r *http.Request, _ httprouter.Params)
 	// Create the teacher.
 	teacher := model.Teacher{Short: short, Name: name, Sex: sex}
 	teacher.Create()
+	model.Audit(session.Username, model.AuditCreate, model.AuditTeacher, teacher.Short, nil, teacher)
 
 	unknown := model.UnknownTeacher{Short: short}
 	unknown.Delete()
@@ -126,64 +130,72 @@ func showTeachers(w http.ResponseWriter, r *http.Request, message string) {
 	}
 }
 
-// NewTeachers serves the upload form to submit multiple teacher records.
+// NewTeachers serves the upload form to import multiple teacher records.
 func NewTeachers(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
-	template, err := template.ParseFiles("templates/base.html", "templates/teacher/upload.html")
-	if err != nil {
-		log.Printf("error: %v\n", err)
+	redirected, _ := ensureLoggedIn(w, r)
+	if redirected {
+		return
 	}
 
-	err = template.Execute(w, nil)
-	if err != nil {
-		log.Printf("error: %v\n", err)
-	}
+	showTeacherUpload(w, r, nil)
 }
 
-// CreateTeachers creates multiple teachers from json upload and serves the list
-// of all teachers.
+// CreateTeachers reads the teachers of an uploaded CSV or JSON file and serves
+// a preview of the import. The import is applied by CommitTeachers.
 func CreateTeachers(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
 	redirected, _ := ensureLoggedIn(w, r)
 	if redirected {
 		return
 	}
 
-	r.ParseMultipartForm(16384)
-	file, _, err := r.FormFile("teacherjson")
+	data, err := readImportFile(r, "teacherjson")
 	if err != nil {
-		log.Printf("error recieving teacher file from upload: %v\n", err)
+		showTeacherUpload(w, r, simpleMessage(err.Error(), false))
 		return
 	}
-	defer file.Close()
-
-	bytes, err := ioutil.ReadAll(file)
+	rows, err := importer.ReadTeachers(data)
 	if err != nil {
 		log.Printf("error reading teacher file: %v\n", err)
+		showTeacherUpload(w, r, simpleMessage("Die Datei konnte nicht gelesen werden: "+err.Error(), false))
 		return
 	}
 
-	type tmpTeacher struct {
-		model.Teacher
-		Compellation string
+	preview := importPreview{Action: "/teachers/upload/commit", Token: storePendingImport(pendingImport{teachers: rows})}
+	preview.addTeachers(rows)
+	showImportPreview(w, "Lehrer importieren", preview)
+}
+
+// CommitTeachers applies a previewed teacher import and serves the list of all
+// teachers with a summary.
+func CommitTeachers(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	redirected, session := ensureLoggedIn(w, r)
+	if redirected {
+		return
 	}
-	teachers := []tmpTeacher{}
-	err = json.Unmarshal(bytes, &teachers)
-	if err != nil {
-		log.Printf("error unmarshaling teacher data: %v\n", err)
+
+	r.ParseForm()
+	pending, ok := takePendingImport(r.Form.Get("token"))
+	if !ok {
+		showTeacherUpload(w, r, simpleMessage("Der Import ist abgelaufen. Bitte die Datei erneut hochladen.", false))
 		return
 	}
-	for _, teacher := range teachers {
-		if teacher.Compellation == "Herr" {
-			teacher.Sex = "m"
-		} else {
-			teacher.Sex = "w"
-		}
-		teacher.Create()
 
-		unknown := model.UnknownTeacher{Short: teacher.Short}
-		unknown.Delete()
+	summary := importer.ApplyTeachers(pending.teachers, r.Form.Get("update") != "")
+	auditTeacherRows(session.Username, pending.teachers)
+	showTeachers(w, r, "Import abgeschlossen: "+summary.String()+".")
+}
+
+// showTeacherUpload is a helper function to show the upload form.
+func showTeacherUpload(w http.ResponseWriter, r *http.Request, data *generalTemplateData) {
+	template, err := template.ParseFiles("templates/base.html", "templates/teacher/upload.html")
+	if err != nil {
+		log.Printf("error: %v\n", err)
 	}
 
-	http.Redirect(w, r, "/teachers", http.StatusSeeOther)
+	err = template.Execute(w, data)
+	if err != nil {
+		log.Printf("error: %v\n", err)
+	}
 }
 
 // GetTeacher serves one teacher.
@@ -234,7 +246,12 @@ func EditTeacher(w http.ResponseWriter, r *http.Request, params httprouter.Param
 	}
 	teacher.Read()
 
-	// Execute template with teacher as template data.
+	showTeacherEdit(w, teacher, nil)
+}
+
+// showTeacherEdit is a helper function to show the form to edit a teacher together
+// with the teacher's aliases and former shorts.
+func showTeacherEdit(w http.ResponseWriter, teacher model.Teacher, data *generalTemplateData) {
 	template, err := template.ParseFiles("templates/base.html", "templates/teacher/edit.html")
 	if err != nil {
 		log.Printf("error: %v\n", err)
@@ -243,8 +260,15 @@ func EditTeacher(w http.ResponseWriter, r *http.Request, params httprouter.Param
 	templateData := struct {
 		generalTemplateData
 		model.Teacher
+		Aliases []model.Alias
+		Renames []model.Rename
 	}{}
+	if data != nil {
+		templateData.generalTemplateData = *data
+	}
 	templateData.Teacher = teacher
+	templateData.Aliases = model.ReadAliases(model.AliasTeacher, teacher.Short)
+	templateData.Renames = model.ReadRenames(model.AliasTeacher, teacher.Short)
 
 	err = template.Execute(w, &templateData)
 	if err != nil {
@@ -254,7 +278,7 @@ func EditTeacher(w http.ResponseWriter, r *http.Request, params httprouter.Param
 
 // UpdateTeacher updates a teacher with the uploaded information.
 func UpdateTeacher(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
-	redirected, _ := ensureLoggedIn(w, r)
+	redirected, session := ensureLoggedIn(w, r)
 	if redirected {
 		return
 	}
@@ -265,6 +289,7 @@ func UpdateTeacher(w http.ResponseWriter, r *http.Request, params httprouter.Par
 		http.NotFound(w, r)
 		return
 	}
+	teacher.Read()
 
 	// Parse and validate teacher data.
 	r.ParseForm()
@@ -272,7 +297,7 @@ func UpdateTeacher(w http.ResponseWriter, r *http.Request, params httprouter.Par
 	name := html.EscapeString(r.Form.Get("name"))
 	sex := html.EscapeString(r.Form.Get("sex"))
 
-	valid, message := validateTeacherData(short, name, sex)
+	valid, message := validateTeacherData(nshort, name, sex)
 	if !valid {
 		http.Error(w, message, http.StatusNotAcceptable)
 		return
@@ -281,13 +306,23 @@ func UpdateTeacher(w http.ResponseWriter, r *http.Request, params httprouter.Par
 	// Update teacher and send the new URL back to the client.
 	// It might have changed with an update of short.
 	updTeacher := model.Teacher{Short: nshort, Name: name, Sex: sex}
-	updTeacher.UpdateShort(short)
+	if err := updTeacher.UpdateShort(short); err == model.ErrShortTaken {
+		http.Error(w, fmt.Sprintf("Das Kürzel %s ist schon vergeben.", nshort), http.StatusConflict)
+		return
+	} else if err != nil {
+		log.Printf("error renaming teacher %s: %v\n", short, err)
+		http.Error(w, "Der Lehrer konnte nicht gespeichert werden.", http.StatusInternalServerError)
+		return
+	}
+	updTeacher.Read()
+	model.Audit(session.Username, model.AuditUpdate, model.AuditTeacher, short, teacher, updTeacher)
 	w.Write([]byte(fmt.Sprintf("/teacher/%s", nshort)))
 }
 
-// DeleteTeacher deletes a teacher and serves nothing (empty 200 OK response).
+// DeleteTeacher moves a teacher to the trash and serves the URL undoing it, see
+// writeUndo.
 func DeleteTeacher(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
-	redirected, _ := ensureLoggedIn(w, r)
+	redirected, session := ensureLoggedIn(w, r)
 	if redirected {
 		return
 	}
@@ -295,13 +330,43 @@ func DeleteTeacher(w http.ResponseWriter, r *http.Request, params httprouter.Par
 	short := html.EscapeString(params.ByName("short"))
 	teacher := model.Teacher{Short: short}
 	if teacher.Exists() {
-		teacher.Delete()
+		teacher.Read()
+		before := teacher
+		teacher.Archive(time.Now(), time.Time{})
+		writeUndo(w, model.Audit(session.Username, model.AuditArchive, model.AuditTeacher, short, before, teacher))
 	} else {
 		http.NotFound(w, r)
 		return
 	}
 }
 
+// ArchiveTeacher moves a teacher to the trash for the time range sent in the form
+// and serves the form to edit the teacher again.
+func ArchiveTeacher(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+	redirected, session := ensureLoggedIn(w, r)
+	if redirected {
+		return
+	}
+
+	teacher := model.Teacher{Short: params.ByName("short")}
+	if !teacher.Exists() {
+		http.NotFound(w, r)
+		return
+	}
+	teacher.Read()
+
+	r.ParseForm()
+	from, until, message := parseArchiveRange(r.Form.Get("from"), r.Form.Get("until"))
+	if message != "" {
+		showTeacherEdit(w, teacher, simpleMessage(message, false))
+		return
+	}
+	before := teacher
+	teacher.Archive(from, until)
+	id := model.Audit(session.Username, model.AuditArchive, model.AuditTeacher, teacher.Short, before, teacher)
+	showTeacherEdit(w, teacher, undoMessage(archiveMessage(teacher.Short, from, until), id))
+}
+
 func validateTeacherData(short, name, sex string) (valid bool, message string) {
 	if sex != "m" && sex != "w" {
 		return false, "Falsche Eingabe: Herr: \"m\", Frau: \"w\""
@@ -0,0 +1,88 @@
+package controller
+
+import (
+	"fmt"
+	"html"
+	"html/template"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/hkohlsaat/vtr/model"
+	"github.com/julienschmidt/httprouter"
+)
+
+// GetAPITokens serves the list of all API tokens.
+func GetAPITokens(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	redirected, _ := ensureLoggedIn(w, r)
+	if redirected {
+		return
+	}
+
+	showAPITokens(w, r, nil, "")
+}
+
+// CreateAPIToken creates a new API token and serves the list of all tokens
+// together with the new token's secret. The secret can't be shown again.
+func CreateAPIToken(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	redirected, session := ensureLoggedIn(w, r)
+	if redirected {
+		return
+	}
+
+	r.ParseForm()
+	name := html.EscapeString(r.Form.Get("name"))
+	if len(name) == 0 {
+		showAPITokens(w, r, simpleMessage("Der Name ist zu kurz.", false).Messages, "")
+		return
+	}
+
+	token, secret := model.CreateAPIToken(name)
+	model.Audit(session.Username, model.AuditCreate, model.AuditToken, token.Name, nil, token)
+	message := fmt.Sprintf("%s wurde erstellt. Der Schlüssel wird nur jetzt angezeigt.", name)
+	showAPITokens(w, r, simpleMessage(message, true).Messages, secret)
+}
+
+// DeleteAPIToken deletes an API token and serves nothing (empty 200 OK response).
+func DeleteAPIToken(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+	redirected, session := ensureLoggedIn(w, r)
+	if redirected {
+		return
+	}
+
+	id, _ := strconv.ParseInt(params.ByName("id"), 10, 64)
+	token := model.APIToken{ID: id}
+	if token.Exists() {
+		for _, t := range model.ReadAllAPITokens() {
+			if t.ID == token.ID {
+				token = t
+			}
+		}
+		token.Delete()
+		model.Audit(session.Username, model.AuditDelete, model.AuditToken, token.Name, token, nil)
+	} else {
+		http.NotFound(w, r)
+		return
+	}
+}
+
+// showAPITokens is a helper function to show a list of all API tokens. secret
+// is the secret of a token just created.
+func showAPITokens(w http.ResponseWriter, r *http.Request, messages []templateMessage, secret string) {
+	templateData := struct {
+		generalTemplateData
+		Tokens []model.APIToken
+		Secret string
+	}{Tokens: model.ReadAllAPITokens(), Secret: secret}
+	templateData.Messages = messages
+
+	template, err := template.ParseFiles("templates/base.html", "templates/token/index.html")
+	if err != nil {
+		log.Printf("error: %v\n", err)
+	}
+
+	err = template.Execute(w, &templateData)
+	if err != nil {
+		log.Printf("error: %v\n", err)
+	}
+}
@@ -0,0 +1,144 @@
+package controller
+
+import (
+	"fmt"
+	"html/template"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/hkohlsaat/vtr/model"
+	"github.com/julienschmidt/httprouter"
+)
+
+// GetTrash serves the archived teachers and subjects.
+func GetTrash(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	redirected, _ := ensureLoggedIn(w, r)
+	if redirected {
+		return
+	}
+
+	showTrash(w, nil)
+}
+
+// RestoreTrash takes a teacher or subject out of the trash.
+func RestoreTrash(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+	redirected, session := ensureLoggedIn(w, r)
+	if redirected {
+		return
+	}
+
+	short := params.ByName("short")
+	switch params.ByName("kind") {
+	case "teachers":
+		teacher := model.Teacher{Short: short}
+		if !teacher.Exists() {
+			http.NotFound(w, r)
+			return
+		}
+		teacher.Read()
+		before := teacher
+		teacher.Restore()
+		model.Audit(session.Username, model.AuditRestore, model.AuditTeacher, short, before, teacher)
+	case "subjects":
+		subject := model.Subject{Short: short}
+		if !subject.Exists() {
+			http.NotFound(w, r)
+			return
+		}
+		subject.Read()
+		before := subject
+		subject.Restore()
+		model.Audit(session.Username, model.AuditRestore, model.AuditSubject, short, before, subject)
+	default:
+		http.NotFound(w, r)
+		return
+	}
+	showTrash(w, simpleMessage(fmt.Sprintf("%s wurde wiederhergestellt.", short), true))
+}
+
+// PurgeTrash deletes an archived teacher or subject for good and serves the
+// URL undoing it, see writeUndo.
+func PurgeTrash(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+	redirected, session := ensureLoggedIn(w, r)
+	if redirected {
+		return
+	}
+
+	short := params.ByName("short")
+	switch params.ByName("kind") {
+	case "teachers":
+		teacher := model.Teacher{Short: short}
+		if teacher.Read(); !teacher.ArchivedFrom.IsZero() {
+			deleted := teacher.WithAliases()
+			teacher.Delete()
+			writeUndo(w, model.Audit(session.Username, model.AuditDelete, model.AuditTeacher, short, deleted, nil))
+			return
+		}
+	case "subjects":
+		subject := model.Subject{Short: short}
+		if subject.Read(); !subject.ArchivedFrom.IsZero() {
+			deleted := subject.WithAliases()
+			subject.Delete()
+			writeUndo(w, model.Audit(session.Username, model.AuditDelete, model.AuditSubject, short, deleted, nil))
+			return
+		}
+	}
+	http.NotFound(w, r)
+}
+
+// parseArchiveRange reads the dates a teacher or subject is archived from and
+// until, e.g. "2016-10-17". Without from it is archived now, without until
+// until it is restored. The message tells why the dates are invalid.
+func parseArchiveRange(fromValue, untilValue string) (from, until time.Time, message string) {
+	var err error
+	from = time.Now()
+	if fromValue != "" {
+		if from, err = time.ParseInLocation("2006-01-02", fromValue, time.Local); err != nil {
+			return from, until, fmt.Sprintf("Das Datum %s ist ungültig.", fromValue)
+		}
+	}
+	if untilValue != "" {
+		if until, err = time.ParseInLocation("2006-01-02", untilValue, time.Local); err != nil {
+			return from, until, fmt.Sprintf("Das Datum %s ist ungültig.", untilValue)
+		}
+		if !until.After(from) {
+			return from, until, "Das Ende muss nach dem Beginn liegen."
+		}
+	}
+	return from, until, ""
+}
+
+// archiveMessage tells when the teacher or subject with the short is in the
+// trash.
+func archiveMessage(short string, from, until time.Time) string {
+	if until.IsZero() {
+		return fmt.Sprintf("%s ist ab dem %s im Papierkorb.", short, from.Format("02.01.2006"))
+	}
+	return fmt.Sprintf("%s ist vom %s bis zum %s im Papierkorb.", short, from.Format("02.01.2006"), until.Format("02.01.2006"))
+}
+
+// showTrash is a helper function to show the archived teachers and subjects.
+func showTrash(w http.ResponseWriter, data *generalTemplateData) {
+	templateData := struct {
+		generalTemplateData
+		Teachers []model.Teacher
+		Subjects []model.Subject
+	}{
+		Teachers: model.ReadArchivedTeachers(),
+		Subjects: model.ReadArchivedSubjects(),
+	}
+	if data != nil {
+		templateData.generalTemplateData = *data
+	}
+
+	template, err := template.ParseFiles("templates/base.html", "templates/trash/index.html")
+	if err != nil {
+		log.Printf("error: %v\n", err)
+	}
+
+	err = template.Execute(w, &templateData)
+	if err != nil {
+		log.Printf("error: %v\n", err)
+	}
+}
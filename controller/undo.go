@@ -0,0 +1,131 @@
+package controller
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/hkohlsaat/vtr/export"
+	"github.com/hkohlsaat/vtr/model"
+	"github.com/julienschmidt/httprouter"
+)
+
+// undoDuration is the time a destructive action can be undone with its token.
+const undoDuration = 10 * time.Minute
+
+// undo is the audit entry of a destructive action which can be undone.
+type undo struct {
+	id      int64
+	expires time.Time
+}
+
+var undos = struct {
+	sync.Mutex
+	undos map[string]undo
+}{undos: make(map[string]undo)}
+
+// storeUndo keeps the audit entry with the id to be undone and returns the
+// URL undoing it, see UndoChange.
+func storeUndo(id int64) string {
+	token := randomToken()
+
+	undos.Lock()
+	defer undos.Unlock()
+	for t, u := range undos.undos {
+		if u.expires.Before(time.Now()) {
+			delete(undos.undos, t)
+		}
+	}
+	undos.undos[token] = undo{id: id, expires: time.Now().Add(undoDuration)}
+	return "/undo/" + token
+}
+
+// takeUndo returns the id of the audit entry stored with the token and
+// removes it, so it is only undone once.
+func takeUndo(token string) (int64, bool) {
+	undos.Lock()
+	defer undos.Unlock()
+	u, ok := undos.undos[token]
+	delete(undos.undos, token)
+	return u.id, ok && u.expires.After(time.Now())
+}
+
+// writeUndo serves the URL undoing the audited action as the response of a
+// DELETE request, for the page to offer undoing it.
+func writeUndo(w http.ResponseWriter, id int64) {
+	if id != 0 {
+		fmt.Fprint(w, storeUndo(id))
+	}
+}
+
+// undoMessage is a message about the audited action offering to undo it.
+func undoMessage(message string, id int64) *generalTemplateData {
+	data := simpleMessage(message, true)
+	if id != 0 {
+		data.Messages[0].Undo = storeUndo(id)
+	}
+	return data
+}
+
+// UndoChange reverts the action the token was handed out for and redirects
+// back to the page it was undone on.
+func UndoChange(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+	redirected, session := ensureLoggedIn(w, r)
+	if redirected {
+		return
+	}
+
+	id, ok := takeUndo(params.ByName("token"))
+	entry := model.AuditEntry{ID: id}
+	if !ok || !entry.Read() {
+		http.Error(w, "Die Zeit zum Rückgängigmachen ist abgelaufen.", http.StatusGone)
+		return
+	}
+	if _, err := entry.Revert(session.Username); err != nil {
+		http.Error(w, revertMessage(err), http.StatusConflict)
+		return
+	}
+
+	back := r.Referer()
+	if back == "" {
+		back = "/"
+	}
+	http.Redirect(w, r, back, http.StatusSeeOther)
+}
+
+// RevertAudit reverts the change of an audit entry and serves the audit log
+// again.
+func RevertAudit(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+	redirected, session := ensureLoggedIn(w, r)
+	if redirected {
+		return
+	}
+
+	id, _ := strconv.ParseInt(params.ByName("id"), 10, 64)
+	entry := model.AuditEntry{ID: id}
+	if !entry.Read() {
+		http.NotFound(w, r)
+		return
+	}
+	if _, err := entry.Revert(session.Username); err != nil {
+		showAudit(w, nil, simpleMessage(revertMessage(err), false))
+		return
+	}
+	message := fmt.Sprintf("Die Änderung an %s %s wurde rückgängig gemacht.", export.AuditEntityText(entry.Entity), entry.Key)
+	showAudit(w, nil, simpleMessage(message, true))
+}
+
+// revertMessage tells why a change couldn't be reverted.
+func revertMessage(err error) string {
+	switch err {
+	case model.ErrNotRevertible:
+		return "Diese Änderung kann nicht rückgängig gemacht werden."
+	case model.ErrRevertConflict:
+		return "Die Änderung kann nicht rückgängig gemacht werden, weil sich seitdem etwas geändert hat."
+	case model.ErrShortTaken:
+		return "Die Änderung kann nicht rückgängig gemacht werden, weil das Kürzel vergeben ist."
+	}
+	return fmt.Sprintf("Die Änderung kann nicht rückgängig gemacht werden: %v", err)
+}
@@ -0,0 +1,203 @@
+package controller
+
+import (
+	"fmt"
+	"html/template"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/hkohlsaat/vtr/model"
+	"github.com/julienschmidt/httprouter"
+)
+
+// unknownEntry is an unknown short with the teachers or subjects it may stand
+// for.
+type unknownEntry struct {
+	// Kind is "teachers" or "subjects" as used in the URLs.
+	Kind        string
+	Short       string
+	FirstSeen   time.Time
+	LastSeen    time.Time
+	Count       int
+	Samples     []string
+	Suggestions []model.Suggestion
+}
+
+// GetUnknown serves the unknown shorts with suggestions what they stand for.
+func GetUnknown(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	redirected, _ := ensureLoggedIn(w, r)
+	if redirected {
+		return
+	}
+
+	showUnknown(w, nil)
+}
+
+// AliasUnknown makes an unknown short an alias of the teacher or subject with
+// the short sent in the form.
+func AliasUnknown(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+	redirected, session := ensureLoggedIn(w, r)
+	if redirected {
+		return
+	}
+
+	r.ParseForm()
+	alias := model.Alias{Alias: params.ByName("short"), Short: r.Form.Get("short")}
+	var exists bool
+	switch params.ByName("kind") {
+	case "teachers":
+		alias.Kind = model.AliasTeacher
+		teacher := model.Teacher{Short: alias.Short}
+		exists = teacher.Exists()
+	case "subjects":
+		alias.Kind = model.AliasSubject
+		subject := model.Subject{Short: alias.Short}
+		exists = subject.Exists()
+	default:
+		http.NotFound(w, r)
+		return
+	}
+	if !exists {
+		showUnknown(w, simpleMessage(fmt.Sprintf("Es gibt kein Kürzel %s.", alias.Short), false))
+		return
+	}
+
+	showUnknown(w, simpleMessage(createAlias(session.Username, alias)))
+}
+
+// IgnoreUnknown stops listing an unknown short. With "show" sent in the form
+// an ignored short is listed again.
+func IgnoreUnknown(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+	redirected, session := ensureLoggedIn(w, r)
+	if redirected {
+		return
+	}
+
+	r.ParseForm()
+	ignored := r.Form.Get("show") == ""
+	short := params.ByName("short")
+	switch params.ByName("kind") {
+	case "teachers":
+		unknown := model.UnknownTeacher{Short: short}
+		if !unknown.Read() {
+			http.NotFound(w, r)
+			return
+		}
+		before := unknown
+		unknown.SetIgnored(ignored)
+		model.Audit(session.Username, model.AuditUpdate, model.AuditUnknownTeacher, short, before, unknown)
+	case "subjects":
+		unknown := model.UnknownSubject{Short: short}
+		if !unknown.Read() {
+			http.NotFound(w, r)
+			return
+		}
+		before := unknown
+		unknown.SetIgnored(ignored)
+		model.Audit(session.Username, model.AuditUpdate, model.AuditUnknownSubject, short, before, unknown)
+	default:
+		http.NotFound(w, r)
+		return
+	}
+	showUnknown(w, nil)
+}
+
+// CreateIgnorePattern adds a pattern of shorts to remove from plans.
+func CreateIgnorePattern(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	redirected, session := ensureLoggedIn(w, r)
+	if redirected {
+		return
+	}
+
+	r.ParseForm()
+	pattern := model.IgnorePattern{Pattern: r.Form.Get("pattern")}
+	if pattern.Pattern == "" {
+		showUnknown(w, simpleMessage("Das Muster ist leer.", false))
+		return
+	}
+	if err := pattern.Create(); err != nil {
+		showUnknown(w, simpleMessage(fmt.Sprintf("Das Muster %s ist ungültig: %v", pattern.Pattern, err), false))
+		return
+	}
+	model.Audit(session.Username, model.AuditCreate, model.AuditIgnorePattern, pattern.Pattern, nil, pattern)
+	showUnknown(w, simpleMessage("Das Muster gilt ab dem nächsten Plan.", true))
+}
+
+// DeleteIgnorePattern deletes a pattern and serves the URL undoing it.
+func DeleteIgnorePattern(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+	redirected, session := ensureLoggedIn(w, r)
+	if redirected {
+		return
+	}
+
+	id, _ := strconv.ParseInt(params.ByName("id"), 10, 64)
+	pattern := model.IgnorePattern{ID: id}
+	if pattern.Exists() {
+		for _, p := range model.ReadAllIgnorePatterns() {
+			if p.ID == pattern.ID {
+				pattern = p
+			}
+		}
+		pattern.Delete()
+		writeUndo(w, model.Audit(session.Username, model.AuditDelete, model.AuditIgnorePattern, pattern.Pattern, pattern, nil))
+	} else {
+		http.NotFound(w, r)
+		return
+	}
+}
+
+// deleteUnknown removes the short from the unknown shorts of the alias kind.
+func deleteUnknown(kind, short string) {
+	if kind == model.AliasTeacher {
+		unknown := model.UnknownTeacher{Short: short}
+		unknown.Delete()
+	} else {
+		unknown := model.UnknownSubject{Short: short}
+		unknown.Delete()
+	}
+}
+
+// showUnknown is a helper function to show the unknown shorts.
+func showUnknown(w http.ResponseWriter, data *generalTemplateData) {
+	templateData := struct {
+		generalTemplateData
+		Teachers        []unknownEntry
+		Subjects        []unknownEntry
+		IgnoredTeachers []model.UnknownTeacher
+		IgnoredSubjects []model.UnknownSubject
+		AllTeachers     []model.Teacher
+		AllSubjects     []model.Subject
+		Patterns        []model.IgnorePattern
+	}{
+		IgnoredTeachers: model.ReadIgnoredUnknownTeachers(),
+		IgnoredSubjects: model.ReadIgnoredUnknownSubjects(),
+		AllTeachers:     model.ReadAllTeachers(),
+		AllSubjects:     model.ReadAllSubjects(),
+		Patterns:        model.ReadAllIgnorePatterns(),
+	}
+	if data != nil {
+		templateData.generalTemplateData = *data
+	}
+	for _, u := range model.ReadAllUnknownTeachers() {
+		templateData.Teachers = append(templateData.Teachers, unknownEntry{Kind: "teachers", Short: u.Short,
+			FirstSeen: u.FirstSeen, LastSeen: u.LastSeen, Count: u.Count, Samples: u.Samples,
+			Suggestions: model.SuggestTeachers(u.Short)})
+	}
+	for _, u := range model.ReadAllUnknownSubjects() {
+		templateData.Subjects = append(templateData.Subjects, unknownEntry{Kind: "subjects", Short: u.Short,
+			FirstSeen: u.FirstSeen, LastSeen: u.LastSeen, Count: u.Count, Samples: u.Samples,
+			Suggestions: model.SuggestSubjects(u.Short)})
+	}
+
+	template, err := template.ParseFiles("templates/base.html", "templates/unknown/index.html")
+	if err != nil {
+		log.Printf("error: %v\n", err)
+	}
+
+	err = template.Execute(w, &templateData)
+	if err != nil {
+		log.Printf("error: %v\n", err)
+	}
+}
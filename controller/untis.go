@@ -0,0 +1,109 @@
+package controller
+
+import (
+	"html/template"
+	"log"
+	"net/http"
+
+	"github.com/hkohlsaat/vtr/importer"
+	"github.com/julienschmidt/httprouter"
+)
+
+// GetUntis serves the form to synchronize teachers and subjects with the
+// Untis export files GPU004 and GPU006.
+func GetUntis(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	redirected, _ := ensureLoggedIn(w, r)
+	if redirected {
+		return
+	}
+
+	showUntis(w, nil)
+}
+
+// CreateUntisImport reads the uploaded Untis export files and serves a preview
+// of the changes.
+func CreateUntisImport(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	redirected, _ := ensureLoggedIn(w, r)
+	if redirected {
+		return
+	}
+
+	var pending pendingImport
+	if hasImportFile(r, "gpu004") {
+		data, err := readImportFile(r, "gpu004")
+		if err == nil {
+			pending.teachers, err = importer.ReadGPUTeachers(data)
+		}
+		if err != nil {
+			log.Printf("error reading GPU004: %v\n", err)
+			showUntis(w, simpleMessage("Die Lehrerdatei (GPU004) konnte nicht gelesen werden: "+err.Error(), false))
+			return
+		}
+	}
+	if hasImportFile(r, "gpu006") {
+		data, err := readImportFile(r, "gpu006")
+		if err == nil {
+			pending.subjects, err = importer.ReadGPUSubjects(data)
+		}
+		if err != nil {
+			log.Printf("error reading GPU006: %v\n", err)
+			showUntis(w, simpleMessage("Die Fächerdatei (GPU006) konnte nicht gelesen werden: "+err.Error(), false))
+			return
+		}
+	}
+	if pending.teachers == nil && pending.subjects == nil {
+		showUntis(w, simpleMessage("Es wurde keine Datei hochgeladen.", false))
+		return
+	}
+
+	preview := importPreview{Action: "/untis/commit", Kinds: true}
+	preview.addTeachers(pending.teachers)
+	preview.addSubjects(pending.subjects)
+	preview.Token = storePendingImport(pending)
+	showImportPreview(w, "Abgleich mit Untis", preview)
+}
+
+// CommitUntisImport applies a previewed synchronization with Untis.
+func CommitUntisImport(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	redirected, session := ensureLoggedIn(w, r)
+	if redirected {
+		return
+	}
+
+	r.ParseForm()
+	pending, ok := takePendingImport(r.Form.Get("token"))
+	if !ok {
+		showUntis(w, simpleMessage("Der Abgleich ist abgelaufen. Bitte die Dateien erneut hochladen.", false))
+		return
+	}
+
+	update := r.Form.Get("update") != ""
+	var messages []string
+	if pending.teachers != nil {
+		messages = append(messages, "Lehrer: "+importer.ApplyTeachers(pending.teachers, update).String()+".")
+		auditTeacherRows(session.Username, pending.teachers)
+	}
+	if pending.subjects != nil {
+		messages = append(messages, "Fächer: "+importer.ApplySubjects(pending.subjects, update).String()+".")
+		auditSubjectRows(session.Username, pending.subjects)
+	}
+
+	data := &generalTemplateData{}
+	for _, message := range messages {
+		data.Messages = append(data.Messages, templateMessage{Text: message, Positive: true})
+	}
+	showUntis(w, data)
+}
+
+// showUntis is a helper function to show the synchronization form.
+func showUntis(w http.ResponseWriter, data *generalTemplateData) {
+	template, err := template.ParseFiles("templates/base.html", "templates/untis/index.html")
+	if err != nil {
+		log.Printf("error: %v\n", err)
+	}
+
+	err = template.Execute(w, data)
+	if err != nil {
+		log.Printf("error: %v\n", err)
+	}
+}
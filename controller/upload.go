@@ -0,0 +1,170 @@
+package controller
+
+import (
+	"fmt"
+	"html/template"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/hkohlsaat/vtr/model"
+	"github.com/julienschmidt/httprouter"
+)
+
+// GetPlanUploads serves the list of all stored plan uploads.
+func GetPlanUploads(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	redirected, _ := ensureLoggedIn(w, r)
+	if redirected {
+		return
+	}
+
+	showPlanUploads(w, r, nil)
+}
+
+// showPlanUploads is a helper function to show a list of all plan uploads.
+func showPlanUploads(w http.ResponseWriter, r *http.Request, messages []templateMessage) {
+	templateData := struct {
+		generalTemplateData
+		Uploads []model.PlanUpload
+	}{Uploads: model.ReadAllPlanUploads()}
+	templateData.Messages = messages
+
+	template, err := template.ParseFiles("templates/base.html", "templates/plan/index.html")
+	if err != nil {
+		log.Printf("error: %v\n", err)
+	}
+
+	err = template.Execute(w, &templateData)
+	if err != nil {
+		log.Printf("error: %v\n", err)
+	}
+}
+
+// GetPlanUploadFile serves the originally uploaded file of a plan upload.
+func GetPlanUploadFile(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+	redirected, _ := ensureLoggedIn(w, r)
+	if redirected {
+		return
+	}
+
+	upload, ok := planUpload(params)
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	file, err := upload.File()
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	// Multi-page uploads are stored as zip archive.
+	name := fmt.Sprintf("vertretungsplan-%d.htm", upload.ID)
+	if http.DetectContentType(file) == "application/zip" {
+		name = fmt.Sprintf("vertretungsplan-%d.zip", upload.ID)
+	}
+	w.Header().Set("content-type", http.DetectContentType(file))
+	w.Header().Set("content-disposition", fmt.Sprintf("attachment; filename=%q", name))
+	w.Write(file)
+}
+
+// ReprocessPlanUpload reads the plan of an upload again from its file and
+// serves the list of all plan uploads.
+func ReprocessPlanUpload(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+	redirected, session := ensureLoggedIn(w, r)
+	if redirected {
+		return
+	}
+
+	upload, ok := planUpload(params)
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+
+	changes, err := upload.Reprocess()
+	if err != nil {
+		log.Printf("error reprocessing plan upload %d: %v\n", upload.ID, err)
+		message := fmt.Sprintf("Der Plan vom %s konnte nicht neu eingelesen werden: %v",
+			upload.Time.Format("02.01.2006 15:04"), err)
+		showPlanUploads(w, r, []templateMessage{templateMessage{Text: message}})
+		return
+	}
+
+	after := upload
+	after.Read()
+	model.Audit(session.Username, model.AuditUpdate, model.AuditPlan, strconv.FormatInt(upload.ID, 10), upload, after)
+
+	message := fmt.Sprintf("Der Plan vom %s wurde neu eingelesen: %s.",
+		upload.Time.Format("02.01.2006 15:04"), summarizeChanges(changes))
+	showPlanUploads(w, r, []templateMessage{templateMessage{Text: message, Positive: true}})
+}
+
+// ComparePlanUpload serves the changes a reprocessing made to a plan.
+func ComparePlanUpload(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+	redirected, _ := ensureLoggedIn(w, r)
+	if redirected {
+		return
+	}
+
+	upload, ok := planUpload(params)
+	if !ok || !upload.Reprocessed {
+		http.NotFound(w, r)
+		return
+	}
+	previous, err := upload.PreviousPlan()
+	if err != nil {
+		log.Printf("error reading previous plan: %v\n", err)
+	}
+	plan, err := upload.Plan()
+	if err != nil {
+		log.Printf("error reading plan: %v\n", err)
+	}
+
+	templateData := struct {
+		generalTemplateData
+		Upload  model.PlanUpload
+		Changes []model.Change
+	}{Upload: upload, Changes: model.Compare(previous, plan)}
+
+	template, err := template.ParseFiles("templates/base.html", "templates/plan/compare.html")
+	if err != nil {
+		log.Printf("error: %v\n", err)
+	}
+
+	err = template.Execute(w, &templateData)
+	if err != nil {
+		log.Printf("error: %v\n", err)
+	}
+}
+
+// planUpload reads the plan upload identified by the id parameter.
+func planUpload(params httprouter.Params) (model.PlanUpload, bool) {
+	id, err := strconv.ParseInt(params.ByName("id"), 10, 64)
+	if err != nil {
+		return model.PlanUpload{}, false
+	}
+	upload := model.PlanUpload{ID: id}
+	if !upload.Exists() {
+		return upload, false
+	}
+	upload.Read()
+	return upload, true
+}
+
+// summarizeChanges describes the numbers of added, changed and removed
+// substitutions, e.g. "2 neu, 1 geändert, 0 entfallen".
+func summarizeChanges(changes []model.Change) string {
+	var added, changed, removed int
+	for _, change := range changes {
+		switch change.Kind() {
+		case model.ChangeAdded:
+			added++
+		case model.ChangeChanged:
+			changed++
+		case model.ChangeRemoved:
+			removed++
+		}
+	}
+	return fmt.Sprintf("%d neu, %d geändert, %d entfallen", added, changed, removed)
+}
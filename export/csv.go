@@ -0,0 +1,37 @@
+package export
+
+import (
+	"encoding/csv"
+	"io"
+)
+
+// CSVOptions tell how to write CSV files.
+type CSVOptions struct {
+	// Separator separates the fields, e.g. ',' or ';'.
+	Separator rune
+	// BOM starts the file with a byte order mark. Excel needs it to read
+	// the file as UTF-8 and show umlauts correctly.
+	BOM bool
+}
+
+// ExcelCSV are the options for CSV files opened with a German Excel: fields
+// separated by semicolons and a byte order mark.
+var ExcelCSV = CSVOptions{Separator: ';', BOM: true}
+
+// WriteCSV writes the table as CSV file in UTF-8 encoding.
+func WriteCSV(w io.Writer, table Table, options CSVOptions) error {
+	if options.BOM {
+		if _, err := io.WriteString(w, "\ufeff"); err != nil {
+			return err
+		}
+	}
+
+	writer := csv.NewWriter(w)
+	if options.Separator != 0 {
+		writer.Comma = options.Separator
+	}
+	writer.UseCRLF = true
+	writer.Write(table.Header)
+	writer.WriteAll(table.Rows)
+	return writer.Error()
+}
@@ -0,0 +1,219 @@
+// Package export writes plans, teachers and subjects as tables in CSV or XLSX
+// format, e.g. to hand them to the school board or to import them into other
+// programs.
+package export
+
+import (
+	"errors"
+	"html"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hkohlsaat/vtr/model"
+)
+
+// Errors returned by Read.
+var (
+	ErrUnknownDataset = errors.New("export: unknown dataset")
+	ErrNoPlan         = errors.New("export: no such plan")
+)
+
+// Datasets are the names of the data which can be exported.
+var Datasets = []string{"plan", "teachers", "subjects", "unknown-teachers", "unknown-subjects", "audit"}
+
+// Table is exported data with a header row.
+type Table struct {
+	Name   string
+	Header []string
+	Rows   [][]string
+}
+
+// Query tells which data to export. For the dataset "plan" the substitutions
+// of the days from From to To are exported as they were last uploaded, or if
+// PlanID is set, the substitutions of this upload. Without both the last
+// upload is exported. For the dataset "audit" the entries selected by Audit
+// are exported, limited to the days from From to To.
+type Query struct {
+	Dataset  string
+	PlanID   int64
+	From, To time.Time
+	Audit    model.AuditFilter
+}
+
+// Read reads the data asked for by the query.
+func Read(query Query) (Table, error) {
+	switch query.Dataset {
+	case "plan":
+		return readPlan(query)
+	case "teachers":
+		return Teachers(model.ReadAllTeachers()), nil
+	case "subjects":
+		return Subjects(model.ReadAllSubjects()), nil
+	case "unknown-teachers":
+		table := Table{Name: "Unbekannte Lehrer", Header: unknownHeader}
+		for _, u := range model.ReadAllUnknownTeachers() {
+			table.Rows = append(table.Rows, unknownRow(u.Short, u.FirstSeen, u.LastSeen, u.Count))
+		}
+		return table, nil
+	case "unknown-subjects":
+		table := Table{Name: "Unbekannte Fächer", Header: unknownHeader}
+		for _, u := range model.ReadAllUnknownSubjects() {
+			table.Rows = append(table.Rows, unknownRow(u.Short, u.FirstSeen, u.LastSeen, u.Count))
+		}
+		return table, nil
+	case "audit":
+		filter := query.Audit
+		if !query.From.IsZero() {
+			filter.From = localDay(query.From)
+		}
+		if !query.To.IsZero() {
+			filter.To = localDay(query.To).AddDate(0, 0, 1)
+		}
+		return Audit(model.ReadAudit(filter)), nil
+	}
+	return Table{}, ErrUnknownDataset
+}
+
+// localDay returns the start of the day in local time, e.g. for a day parsed
+// in UTC.
+func localDay(day time.Time) time.Time {
+	return time.Date(day.Year(), day.Month(), day.Day(), 0, 0, 0, 0, time.Local)
+}
+
+var unknownHeader = []string{"Kürzel", "Zuerst gesehen", "Zuletzt gesehen", "Anzahl"}
+
+func unknownRow(short string, first, last time.Time, count int) []string {
+	row := []string{short, "", "", strconv.Itoa(count)}
+	if !first.IsZero() {
+		row[1], row[2] = first.Format("02.01.2006 15:04"), last.Format("02.01.2006 15:04")
+	}
+	return row
+}
+
+func readPlan(query Query) (Table, error) {
+	if !query.From.IsZero() {
+		to := query.To
+		if to.IsZero() {
+			to = query.From
+		}
+		return Substitutions(model.ReadDays(query.From, to)), nil
+	}
+
+	upload := model.PlanUpload{ID: query.PlanID}
+	if query.PlanID == 0 {
+		last, ok := model.LastPlanUpload()
+		if !ok {
+			return Table{}, ErrNoPlan
+		}
+		upload = last
+	} else if !upload.Exists() {
+		return Table{}, ErrNoPlan
+	}
+	plan, err := upload.Plan()
+	if err != nil {
+		return Table{}, err
+	}
+	plan.Resolve()
+	return Substitutions(plan.Parts), nil
+}
+
+// Teachers returns the table of the teachers.
+func Teachers(teachers []model.Teacher) Table {
+	table := Table{Name: "Lehrer", Header: []string{"Kürzel", "Name", "Geschlecht", "Anrede"}}
+	for _, t := range teachers {
+		// The forms store the values escaped, the importer escapes them again.
+		table.Rows = append(table.Rows, []string{html.UnescapeString(t.Short), html.UnescapeString(t.Name), t.Sex,
+			html.UnescapeString(t.FullName())})
+	}
+	return table
+}
+
+// Subjects returns the table of the subjects.
+func Subjects(subjects []model.Subject) Table {
+	table := Table{Name: "Fächer", Header: []string{"Kürzel", "Name", "Geteilte Klasse"}}
+	for _, s := range subjects {
+		split := "nein"
+		if s.SplitClass {
+			split = "ja"
+		}
+		table.Rows = append(table.Rows, []string{html.UnescapeString(s.Short), html.UnescapeString(s.Name), split})
+	}
+	return table
+}
+
+// auditActions and auditEntities name the actions and entities of the audit
+// log.
+var (
+	auditActions = map[string]string{
+		model.AuditCreate:  "angelegt",
+		model.AuditUpdate:  "geändert",
+		model.AuditDelete:  "gelöscht",
+		model.AuditArchive: "in den Papierkorb gelegt",
+		model.AuditRestore: "wiederhergestellt",
+		model.AuditUpload:  "hochgeladen",
+	}
+	auditEntities = map[string]string{
+		model.AuditTeacher:        "Lehrer",
+		model.AuditSubject:        "Fach",
+		model.AuditAlias:          "Alias",
+		model.AuditUser:           "Benutzer",
+		model.AuditPlan:           "Plan",
+		model.AuditToken:          "API-Schlüssel",
+		model.AuditDisplay:        "Anzeige",
+		model.AuditUnknownTeacher: "Unbekannter Lehrer",
+		model.AuditUnknownSubject: "Unbekanntes Fach",
+		model.AuditIgnorePattern:  "Ignoriertes Muster",
+	}
+)
+
+// AuditActionText returns the German name of an audited action, e.g.
+// "geändert".
+func AuditActionText(action string) string {
+	if text, ok := auditActions[action]; ok {
+		return text
+	}
+	return action
+}
+
+// AuditEntityText returns the German name of an audited entity, e.g. "Lehrer".
+func AuditEntityText(entity string) string {
+	if text, ok := auditEntities[entity]; ok {
+		return text
+	}
+	return entity
+}
+
+// Audit returns the table of the audit log entries.
+func Audit(entries []model.AuditEntry) Table {
+	table := Table{Name: "Protokoll", Header: []string{"Zeit", "Benutzer", "Aktion", "Objekt", "Schlüssel", "Vorher", "Nachher"}}
+	for _, e := range entries {
+		table.Rows = append(table.Rows, []string{e.Time.Format("02.01.2006 15:04:05"), e.Actor,
+			AuditActionText(e.Action), AuditEntityText(e.Entity), e.Key, e.Before, e.After})
+	}
+	return table
+}
+
+var weekdays = [...]string{"Sonntag", "Montag", "Dienstag", "Mittwoch", "Donnerstag", "Freitag", "Samstag"}
+
+// Substitutions returns the table of the substitutions of the days.
+func Substitutions(parts []model.Part) Table {
+	table := Table{Name: "Vertretungen", Header: []string{"Datum", "Wochentag", "Klasse", "Stunde",
+		"Vertretung", "Vertretung (Name)", "statt", "statt (Name)", "Fach", "Fach (Name)",
+		"Art", "Hinweis", "Räume", "Aufgaben von"}}
+	for _, part := range parts {
+		for _, s := range part.Substitutions {
+			var providers []string
+			for _, t := range s.TaskProviders {
+				providers = append(providers, t.Short)
+			}
+			table.Rows = append(table.Rows, []string{
+				part.Day.Format("02.01.2006"), weekdays[part.Day.Weekday()], s.Class, s.Period,
+				s.SubstTeacher.Short, s.SubstTeacher.Name, s.InstdTeacher.Short, s.InstdTeacher.Name,
+				s.InstdSubject.Short, s.InstdSubject.Name, s.Kind, s.Text,
+				strings.Join(s.Rooms, ", "), strings.Join(providers, ", "),
+			})
+		}
+	}
+	return table
+}
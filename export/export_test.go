@@ -0,0 +1,113 @@
+package export
+
+import (
+	"archive/zip"
+	"bytes"
+	"io/ioutil"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hkohlsaat/vtr/model"
+)
+
+var testTable = Table{Name: "Lehrer", Header: []string{"Kürzel", "Name"},
+	Rows: [][]string{{"Md", "Müller"}, {"Sz", "Schulz; Anna"}}}
+
+func TestWriteCSV(t *testing.T) {
+	var b bytes.Buffer
+	if err := WriteCSV(&b, testTable, ExcelCSV); err != nil {
+		t.Fatal(err)
+	}
+	expected := "\ufeffKürzel;Name\r\nMd;Müller\r\nSz;\"Schulz; Anna\"\r\n"
+	if b.String() != expected {
+		t.Errorf("CSV is %q, expected %q.", b.String(), expected)
+	}
+
+	b.Reset()
+	WriteCSV(&b, testTable, CSVOptions{})
+	if !strings.HasPrefix(b.String(), "Kürzel,Name\r\n") {
+		t.Errorf("CSV without options starts with %q.", b.String())
+	}
+}
+
+func TestWriteXLSX(t *testing.T) {
+	var b bytes.Buffer
+	if err := WriteXLSX(&b, testTable, Table{Name: "a/b"}); err != nil {
+		t.Fatal(err)
+	}
+
+	archive, err := zip.NewReader(bytes.NewReader(b.Bytes()), int64(b.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	files := make(map[string]string)
+	for _, f := range archive.File {
+		r, _ := f.Open()
+		content, _ := ioutil.ReadAll(r)
+		files[f.Name] = string(content)
+	}
+
+	for _, name := range []string{"[Content_Types].xml", "_rels/.rels", "xl/workbook.xml", "xl/styles.xml",
+		"xl/_rels/workbook.xml.rels", "xl/worksheets/sheet1.xml", "xl/worksheets/sheet2.xml"} {
+		if _, ok := files[name]; !ok {
+			t.Errorf("%s is missing.", name)
+		}
+	}
+	if !strings.Contains(files["xl/workbook.xml"], `name="a_b"`) {
+		t.Error("Sheet name wasn't made valid.")
+	}
+	sheet := files["xl/worksheets/sheet1.xml"]
+	if !strings.Contains(sheet, `<c r="B3" t="inlineStr"><is><t xml:space="preserve">Schulz; Anna</t></is></c>`) {
+		t.Errorf("Cell B3 is missing in %s", sheet)
+	}
+}
+
+func TestColumnName(t *testing.T) {
+	for index, name := range map[int]string{0: "A", 25: "Z", 26: "AA", 27: "AB", 701: "ZZ", 702: "AAA"} {
+		if columnName(index) != name {
+			t.Errorf("Column %d is called %s, expected %s.", index, columnName(index), name)
+		}
+	}
+}
+
+func TestSubstitutions(t *testing.T) {
+	day := time.Date(2016, 10, 19, 0, 0, 0, 0, time.UTC)
+	table := Substitutions([]model.Part{{Day: day, Substitutions: []model.Substitution{{
+		Class: "5a", Period: "3", SubstTeacher: model.Teacher{Short: "Md", Name: "Müller"},
+		InstdSubject: model.Subject{Short: "M", Name: "Mathematik"}, Rooms: []string{"A1", "A2"},
+	}}}})
+
+	if len(table.Rows) != 1 || len(table.Rows[0]) != len(table.Header) {
+		t.Fatalf("Unexpected table %+v.", table)
+	}
+	row := table.Rows[0]
+	if row[0] != "19.10.2016" || row[1] != "Mittwoch" || row[5] != "Müller" || row[12] != "A1, A2" {
+		t.Errorf("Unexpected row %q.", row)
+	}
+}
+
+func TestWriteGPU(t *testing.T) {
+	table := GPUTeachers([]model.Teacher{{Short: "Md", Name: "M&#39;Müller", Sex: "w"}, {Short: "Ia", Name: "Inaktiv", Inactive: true}})
+	var b bytes.Buffer
+	if err := WriteGPU(&b, table); err != nil {
+		t.Fatal(err)
+	}
+	expected := "Md,M'M\xfcller,,,,,,,,,,,,,,,,1\r\n"
+	if b.String() != expected {
+		t.Errorf("GPU004 is %q, expected %q.", b.String(), expected)
+	}
+}
+
+func TestAudit(t *testing.T) {
+	entry := model.AuditEntry{Time: time.Date(2016, 10, 17, 8, 30, 0, 0, time.Local), Actor: "admin",
+		Action: model.AuditArchive, Entity: model.AuditTeacher, Key: "Md", Before: `{"Short":"Md"}`}
+	table := Audit([]model.AuditEntry{entry})
+	if len(table.Rows) != 1 || table.Rows[0][0] != "17.10.2016 08:30:00" || table.Rows[0][2] != "in den Papierkorb gelegt" ||
+		table.Rows[0][3] != "Lehrer" || table.Rows[0][6] != "" {
+		t.Errorf("Unexpected table %+v.", table)
+	}
+	if AuditActionText("other") != "other" {
+		t.Error("Unknown action isn't kept.")
+	}
+}
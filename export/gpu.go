@@ -0,0 +1,58 @@
+package export
+
+import (
+	"encoding/csv"
+	"html"
+	"io"
+
+	"github.com/hkohlsaat/vtr/model"
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/charmap"
+	"golang.org/x/text/transform"
+)
+
+// GPUTeachers returns the teachers as rows of the Untis export file GPU004:
+// short, name and, in field 18, the sex (1 female, 2 male). Inactive teachers
+// are left out.
+func GPUTeachers(teachers []model.Teacher) Table {
+	table := Table{Name: "GPU004"}
+	for _, t := range teachers {
+		if t.Inactive {
+			continue
+		}
+		row := make([]string, 18)
+		row[0], row[1] = html.UnescapeString(t.Short), html.UnescapeString(t.Name)
+		switch t.Sex {
+		case "w":
+			row[17] = "1"
+		case "m":
+			row[17] = "2"
+		}
+		table.Rows = append(table.Rows, row)
+	}
+	return table
+}
+
+// GPUSubjects returns the subjects as rows of the Untis export file GPU006:
+// short and name.
+func GPUSubjects(subjects []model.Subject) Table {
+	table := Table{Name: "GPU006"}
+	for _, s := range subjects {
+		table.Rows = append(table.Rows, []string{html.UnescapeString(s.Short), html.UnescapeString(s.Name)})
+	}
+	return table
+}
+
+// WriteGPU writes the rows of the table like Untis writes its export files:
+// without header, separated by commas and in Windows-1252 encoding. Characters
+// Windows-1252 lacks are replaced.
+func WriteGPU(w io.Writer, table Table) error {
+	encoded := transform.NewWriter(w, encoding.ReplaceUnsupported(charmap.Windows1252.NewEncoder()))
+	writer := csv.NewWriter(encoded)
+	writer.UseCRLF = true
+	writer.WriteAll(table.Rows)
+	if err := writer.Error(); err != nil {
+		return err
+	}
+	return encoded.Close()
+}
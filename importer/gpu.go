@@ -0,0 +1,139 @@
+package importer
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"html"
+	"io"
+	"strings"
+
+	"github.com/hkohlsaat/vtr/model"
+)
+
+// Fields of the Untis export files, counted from 1 like in the Untis manual.
+const (
+	// GPU004 holds the teachers.
+	gpuTeacherShort = 1
+	gpuTeacherName  = 2
+	gpuTeacherSex   = 18
+	// GPU006 holds the subjects.
+	gpuSubjectShort = 1
+	gpuSubjectName  = 2
+)
+
+// gpuRecord is a line of an Untis export file.
+type gpuRecord struct {
+	line   int
+	fields []string
+}
+
+// field returns the nth field, counted from 1. It is escaped like the forms
+// store values.
+func (r gpuRecord) field(n int) string {
+	if n > len(r.fields) {
+		return ""
+	}
+	return html.EscapeString(strings.TrimSpace(r.fields[n-1]))
+}
+
+// readGPU reads the lines of an Untis export file. The files have no header
+// row and are usually written in Windows-1252.
+func readGPU(data []byte) ([]gpuRecord, error) {
+	text, _ := model.DecodeText(data)
+	reader := csv.NewReader(bytes.NewReader(text))
+	reader.Comma = separator(text)
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+
+	var records []gpuRecord
+	for {
+		fields, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("importer: invalid GPU file: %v", err)
+		}
+		line, _ := reader.FieldPos(0)
+		records = append(records, gpuRecord{line: line, fields: fields})
+	}
+	if len(records) == 0 {
+		return nil, ErrNoRecords
+	}
+	return records, nil
+}
+
+// ReadGPUTeachers reads the teachers of the Untis export file GPU004. Recorded
+// teachers not in the file are returned with StatusMissing, inactive teachers
+// in the file as changed. Names and sexes missing in the file are taken
+// from the recorded teachers.
+func ReadGPUTeachers(data []byte) ([]TeacherRow, error) {
+	records, err := readGPU(data)
+	if err != nil {
+		return nil, err
+	}
+
+	var rows []TeacherRow
+	seen := make(map[string]bool)
+	listed := make(map[string]bool)
+	for _, r := range records {
+		teacher := model.Teacher{Short: r.field(gpuTeacherShort), Name: r.field(gpuTeacherName)}
+		switch strings.ToLower(r.field(gpuTeacherSex)) {
+		case "1", "w", "f":
+			teacher.Sex = "w"
+		case "2", "m":
+			teacher.Sex = "m"
+		}
+		existing := model.Teacher{Short: teacher.Short}
+		if teacher.Short != "" && existing.Exists() {
+			existing.Read()
+			if teacher.Name == "" {
+				teacher.Name = existing.Name
+			}
+			if teacher.Sex == "" {
+				teacher.Sex = existing.Sex
+			}
+		}
+		listed[teacher.Short] = true
+
+		row := TeacherRow{Line: r.line, Teacher: teacher}
+		row.check(seen)
+		rows = append(rows, row)
+	}
+
+	for _, teacher := range model.ReadAllTeachers() {
+		if !listed[teacher.Short] && !teacher.Inactive {
+			rows = append(rows, TeacherRow{Teacher: teacher, Existing: teacher, Status: StatusMissing})
+		}
+	}
+	return rows, nil
+}
+
+// ReadGPUSubjects reads the subjects of the Untis export file GPU006. As the
+// file doesn't tell whether classes are split, this is kept as recorded.
+func ReadGPUSubjects(data []byte) ([]SubjectRow, error) {
+	records, err := readGPU(data)
+	if err != nil {
+		return nil, err
+	}
+
+	var rows []SubjectRow
+	seen := make(map[string]bool)
+	for _, r := range records {
+		subject := model.Subject{Short: r.field(gpuSubjectShort), Name: r.field(gpuSubjectName)}
+		existing := model.Subject{Short: subject.Short}
+		if subject.Short != "" && existing.Exists() {
+			existing.Read()
+			subject.SplitClass = existing.SplitClass
+			if subject.Name == "" {
+				subject.Name = existing.Name
+			}
+		}
+
+		row := SubjectRow{Line: r.line, Subject: subject}
+		row.check(seen)
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
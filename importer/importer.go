@@ -0,0 +1,160 @@
+// Package importer reads teachers and subjects from CSV or JSON files. The
+// rows read are checked against the records in the database first, so they
+// can be previewed before they are applied.
+package importer
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/text/encoding/charmap"
+)
+
+// ErrNoRecords is returned for files without any rows.
+var ErrNoRecords = errors.New("importer: file holds no records")
+
+// States of an imported row.
+const (
+	// StatusNew rows are not in the database yet.
+	StatusNew = "new"
+	// StatusChanged rows differ from the record in the database.
+	StatusChanged = "changed"
+	// StatusUnchanged rows are equal to the record in the database.
+	StatusUnchanged = "unchanged"
+	// StatusDuplicate rows have the short of an earlier row of the file.
+	StatusDuplicate = "duplicate"
+	// StatusInvalid rows lack information or hold invalid values.
+	StatusInvalid = "invalid"
+	// StatusMissing rows are recorded teachers not found in an Untis export.
+	StatusMissing = "missing"
+	// StatusRestored rows are teachers or subjects in the trash without end,
+	// which are restored. Those in the trash until a given day stay there.
+	StatusRestored = "restored"
+)
+
+// Summary counts what was done applying an import.
+type Summary struct {
+	Created int
+	Updated int
+	// Skipped counts the unchanged rows and the changed rows which weren't
+	// applied because updates weren't wanted.
+	Skipped int
+	// Rejected counts duplicate and invalid rows.
+	Rejected int
+	// Deactivated counts the teachers marked inactive, Reactivated the
+	// inactive teachers read again and the teachers and subjects restored.
+	Deactivated int
+	Reactivated int
+}
+
+func (s Summary) String() string {
+	text := fmt.Sprintf("%d neu, %d aktualisiert, %d übersprungen, %d abgelehnt", s.Created, s.Updated, s.Skipped, s.Rejected)
+	if s.Deactivated > 0 || s.Reactivated > 0 {
+		text += fmt.Sprintf(", %d inaktiv, %d wieder aktiv", s.Deactivated, s.Reactivated)
+	}
+	return text
+}
+
+// record is a row of the file with its fields by lower case name.
+type record struct {
+	line   int
+	fields map[string]string
+}
+
+// get returns the first field found with one of the names. It is escaped
+// like the forms store values.
+func (r record) get(names ...string) string {
+	for _, name := range names {
+		if value, ok := r.fields[name]; ok {
+			return html.EscapeString(strings.TrimSpace(value))
+		}
+	}
+	return ""
+}
+
+// readRecords reads the rows of a JSON array of objects or of a CSV file with
+// header row. CSV files may be separated by commas, semicolons or tabs and be
+// encoded in UTF-8 or, as saved by Excel, in Windows-1252.
+func readRecords(data []byte) ([]record, error) {
+	data = bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))
+	if !utf8.Valid(data) {
+		decoded, err := charmap.Windows1252.NewDecoder().Bytes(data)
+		if err != nil {
+			return nil, err
+		}
+		data = decoded
+	}
+
+	var records []record
+	if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && trimmed[0] == '[' {
+		var objects []map[string]interface{}
+		if err := json.Unmarshal(trimmed, &objects); err != nil {
+			return nil, fmt.Errorf("importer: invalid JSON: %v", err)
+		}
+		for i, object := range objects {
+			fields := make(map[string]string)
+			for key, value := range object {
+				if value != nil {
+					fields[strings.ToLower(key)] = fmt.Sprint(value)
+				}
+			}
+			records = append(records, record{line: i + 1, fields: fields})
+		}
+	} else {
+		reader := csv.NewReader(bytes.NewReader(data))
+		reader.Comma = separator(data)
+		reader.FieldsPerRecord = -1
+		rows, err := reader.ReadAll()
+		if err != nil {
+			return nil, fmt.Errorf("importer: invalid CSV: %v", err)
+		}
+		if len(rows) > 0 {
+			header := rows[0]
+			for i, row := range rows[1:] {
+				fields := make(map[string]string)
+				for j, value := range row {
+					if j < len(header) {
+						fields[strings.ToLower(strings.TrimSpace(header[j]))] = value
+					}
+				}
+				// Lines are counted like in a spreadsheet, the header being line 1.
+				records = append(records, record{line: i + 2, fields: fields})
+			}
+		}
+	}
+
+	if len(records) == 0 {
+		return nil, ErrNoRecords
+	}
+	return records, nil
+}
+
+// separator guesses the separator of the CSV file from its first line.
+func separator(data []byte) rune {
+	line := data
+	if i := bytes.IndexByte(data, '\n'); i >= 0 {
+		line = data[:i]
+	}
+	best, count := ',', bytes.Count(line, []byte(","))
+	for _, c := range []rune{';', '\t'} {
+		if n := bytes.Count(line, []byte(string(c))); n > count {
+			best, count = c, n
+		}
+	}
+	return best
+}
+
+// parseBool reads yes/no values like "ja", "true", "x" or "1".
+func parseBool(value string) bool {
+	switch strings.ToLower(value) {
+	case "ja", "j", "yes", "y", "true", "wahr", "x", "1":
+		return true
+	}
+	return false
+}
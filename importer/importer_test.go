@@ -0,0 +1,183 @@
+package importer
+
+import (
+	"testing"
+	"time"
+
+	"github.com/hkohlsaat/vtr/model"
+	"golang.org/x/text/encoding/charmap"
+)
+
+func TestReadTeachers(t *testing.T) {
+	existing := model.Teacher{Short: "ImA", Name: "Alt", Sex: "m"}
+	existing.Create()
+	unchanged := model.Teacher{Short: "ImB", Name: "Gleich", Sex: "w"}
+	unchanged.Create()
+
+	// Excel saves CSV files in Windows-1252.
+	csv, _ := charmap.Windows1252.NewEncoder().String("Kürzel;Name;Geschlecht\n" +
+		"ImA;Älter;m\nImB;Gleich;w\nImC;Neu;w\nImC;Doppelt;m\n;Ohne Kürzel;m\nImD;Ohne Geschlecht;\n")
+	rows, err := ReadTeachers([]byte(csv))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{StatusChanged, StatusUnchanged, StatusNew, StatusDuplicate, StatusInvalid, StatusInvalid}
+	if len(rows) != len(expected) {
+		t.Fatalf("%d rows read, expected %d.", len(rows), len(expected))
+	}
+	for i, row := range rows {
+		if row.Status != expected[i] || row.Line != i+2 {
+			t.Errorf("Row %d is %s in line %d, expected %s.", i, row.Status, row.Line, expected[i])
+		}
+	}
+	if rows[0].Teacher.Name != "Älter" || rows[0].Existing.Name != "Alt" {
+		t.Errorf("Umlaut wasn't read or existing teacher is missing: %+v", rows[0])
+	}
+
+	summary := ApplyTeachers(rows, false)
+	if summary != (Summary{Created: 1, Skipped: 2, Rejected: 3}) {
+		t.Errorf("Unexpected summary %+v.", summary)
+	}
+	existing.Read()
+	if existing.Name != "Alt" {
+		t.Error("Teacher was updated.")
+	}
+
+	rows, _ = ReadTeachers([]byte(csv))
+	summary = ApplyTeachers(rows, true)
+	if summary != (Summary{Updated: 1, Skipped: 2, Rejected: 3}) {
+		t.Errorf("Unexpected summary %+v.", summary)
+	}
+	existing.Read()
+	if existing.Name != "Älter" {
+		t.Error("Teacher wasn't updated.")
+	}
+
+	for _, short := range []string{"ImA", "ImB", "ImC"} {
+		teacher := model.Teacher{Short: short}
+		teacher.Delete()
+	}
+}
+
+func TestReadEscaped(t *testing.T) {
+	// The forms store the values escaped.
+	existing := model.Teacher{Short: "EsA", Name: "O&#39;Neill &amp; Co", Sex: "m"}
+	existing.Create()
+	defer existing.Delete()
+
+	rows, err := ReadTeachers([]byte("Kürzel;Name;Geschlecht\nEsA;O'Neill & Co;m\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 1 || rows[0].Status != StatusUnchanged {
+		t.Errorf("Unexpected rows %+v.", rows)
+	}
+	gpu := `"EsA","O'Neill & Co",,,,,,,,,,,,,,,,"2"` + "\r\n"
+	if rows, _ := ReadGPUTeachers([]byte(gpu)); len(rows) == 0 || rows[0].Status != StatusUnchanged {
+		t.Errorf("Unexpected GPU rows %+v.", rows)
+	}
+}
+
+func TestReadSubjectsJSON(t *testing.T) {
+	// The format of the former JSON upload.
+	json := `[{"Short": "ImM", "Name": "Mathematik", "ConcurrentlyTaught": true},
+		{"short": "ImD", "name": "Deutsch", "split_class": false}]`
+	rows, err := ReadSubjects([]byte(json))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 2 || rows[0].Status != StatusNew || !rows[0].Subject.SplitClass || rows[1].Subject.Name != "Deutsch" {
+		t.Errorf("Unexpected rows %+v.", rows)
+	}
+
+	if _, err := ReadSubjects([]byte("Kürzel,Name\n")); err != ErrNoRecords {
+		t.Errorf("Empty file gave %v, expected ErrNoRecords.", err)
+	}
+}
+
+func TestReadGPUTeachers(t *testing.T) {
+	kept := model.Teacher{Short: "GpA", Name: "Alt", Sex: "m"}
+	kept.Create()
+	gone := model.Teacher{Short: "GpB", Name: "Weg", Sex: "w"}
+	gone.Create()
+	back := model.Teacher{Short: "GpC", Name: "Zurück", Sex: "w"}
+	back.Create()
+	back.SetInactive(true)
+	unknown := model.UnknownTeacher{Short: "GpD"}
+	unknown.Create()
+	defer func() {
+		for _, short := range []string{"GpA", "GpB", "GpC", "GpD"} {
+			teacher := model.Teacher{Short: short}
+			teacher.Delete()
+		}
+	}()
+
+	// GPU004 without sex for the recorded teacher, in Windows-1252.
+	gpu, _ := charmap.Windows1252.NewEncoder().String(`"GpA","Alt",,,,,,,,,,,,,,,,` + "\r\n" +
+		`"GpC","Zurück",,,,,,,,,,,,,,,,"1"` + "\r\n" + `"GpD","Neu",,,,,,,,,,,,,,,,"2"` + "\r\n")
+	rows, err := ReadGPUTeachers([]byte(gpu))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	status := make(map[string]string)
+	for _, row := range rows {
+		status[row.Teacher.Short] = row.Status
+	}
+	expected := map[string]string{"GpA": StatusUnchanged, "GpB": StatusMissing, "GpC": StatusChanged, "GpD": StatusNew}
+	for short, s := range expected {
+		if status[short] != s {
+			t.Errorf("%s is %s, expected %s.", short, status[short], s)
+		}
+	}
+
+	summary := ApplyTeachers(rows, false)
+	if summary.Created != 1 || summary.Deactivated < 1 || summary.Reactivated != 1 {
+		t.Errorf("Unexpected summary %+v.", summary)
+	}
+	gone.Read()
+	back.Read()
+	if !gone.Inactive || back.Inactive {
+		t.Errorf("Teachers weren't (re)activated: %+v, %+v", gone, back)
+	}
+	for _, u := range model.ReadAllUnknownTeachers() {
+		if u.Short == "GpD" {
+			t.Error("Unknown teacher wasn't removed.")
+		}
+	}
+}
+
+func TestReadGPUSubjects(t *testing.T) {
+	split := model.Subject{Short: "GpS", Name: "Sport", SplitClass: true}
+	split.Create()
+	defer split.Delete()
+	archived := model.Subject{Short: "GpR", Name: "Religion"}
+	archived.Create()
+	archived.Archive(time.Now().Add(-time.Hour), time.Time{})
+	defer archived.Delete()
+	leave := model.Subject{Short: "GpL", Name: "Latein"}
+	leave.Create()
+	leave.Archive(time.Now().Add(-time.Hour), time.Now().Add(time.Hour))
+	defer leave.Delete()
+
+	rows, err := ReadGPUSubjects([]byte("GpS;Sport;;\nGpE;;;\nGpR;Religion;;\nGpL;Latein;;\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 4 || rows[0].Status != StatusUnchanged || rows[1].Status != StatusInvalid || rows[1].Line != 2 ||
+		rows[2].Status != StatusRestored || rows[3].Status != StatusUnchanged {
+		t.Errorf("Unexpected rows %+v.", rows)
+	}
+
+	// Subjects in the trash without end are restored, the others stay.
+	if summary := ApplySubjects(rows, false); summary.Reactivated != 1 {
+		t.Errorf("Unexpected summary %+v.", summary)
+	}
+	if leave.Read(); leave.ArchivedUntil.IsZero() {
+		t.Error("Subject in the trash until a day was restored.")
+	}
+	if archived.Read(); !archived.ArchivedFrom.IsZero() {
+		t.Error("Subject wasn't restored.")
+	}
+}
@@ -0,0 +1,111 @@
+package importer
+
+import (
+	"time"
+
+	"github.com/hkohlsaat/vtr/model"
+)
+
+// SubjectRow is a subject read from a file.
+type SubjectRow struct {
+	Line    int
+	Subject model.Subject
+	// Existing is the subject recorded with the same short, if there is one.
+	Existing model.Subject
+	Status   string
+	// Message tells why the row is invalid.
+	Message string
+	// Applied is the change made applying the row, model.AuditCreate or
+	// model.AuditUpdate, or empty if nothing was changed.
+	Applied string
+}
+
+// ReadSubjects reads the subjects from a CSV or JSON file. The columns are
+// "Kürzel" (or "Short"), "Name" and "Geteilte Klasse" ("SplitClass" or
+// "ConcurrentlyTaught"), as written by the export.
+func ReadSubjects(data []byte) ([]SubjectRow, error) {
+	records, err := readRecords(data)
+	if err != nil {
+		return nil, err
+	}
+
+	var rows []SubjectRow
+	seen := make(map[string]bool)
+	for _, r := range records {
+		subject := model.Subject{
+			Short:      r.get("kürzel", "short"),
+			Name:       r.get("name"),
+			SplitClass: parseBool(r.get("geteilte klasse", "splitclass", "split_class", "concurrentlytaught")),
+		}
+
+		row := SubjectRow{Line: r.line, Subject: subject}
+		row.check(seen)
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+// check sets the status of the row. seen holds the shorts of the earlier rows.
+func (row *SubjectRow) check(seen map[string]bool) {
+	subject := row.Subject
+	switch {
+	case subject.Short == "":
+		row.Status, row.Message = StatusInvalid, "Das Kürzel fehlt."
+	case subject.Name == "":
+		row.Status, row.Message = StatusInvalid, "Der Name fehlt."
+	case seen[subject.Short]:
+		row.Status, row.Message = StatusDuplicate, "Das Kürzel steht schon in einer früheren Zeile."
+	default:
+		seen[subject.Short] = true
+		existing := model.Subject{Short: subject.Short}
+		if !existing.Exists() {
+			row.Status = StatusNew
+			return
+		}
+		existing.Read()
+		row.Existing = existing
+		subject.ArchivedFrom, subject.ArchivedUntil = existing.ArchivedFrom, existing.ArchivedUntil
+		switch {
+		case existing.ArchivedAt(time.Now()) && existing.ArchivedUntil.IsZero():
+			row.Status = StatusRestored
+		case existing == subject:
+			row.Status = StatusUnchanged
+		default:
+			row.Status = StatusChanged
+		}
+	}
+}
+
+// ApplySubjects creates the new subjects and, with update, updates the changed
+// and restored ones. Created subjects are removed from the unknown subjects.
+// Restored subjects are taken out of the trash. The change made is set in the
+// rows.
+func ApplySubjects(rows []SubjectRow, update bool) Summary {
+	var summary Summary
+	for i := range rows {
+		row := &rows[i]
+		subject := row.Subject
+		if row.Status == StatusRestored {
+			subject.Restore()
+			row.Applied = model.AuditUpdate
+			summary.Reactivated++
+		}
+		switch {
+		case row.Status == StatusNew && !subject.Exists():
+			subject.Create()
+			unknown := model.UnknownSubject{Short: subject.Short}
+			unknown.Delete()
+			row.Applied = model.AuditCreate
+			summary.Created++
+		case row.Status == StatusChanged && update, row.Status == StatusRestored && update, row.Status == StatusNew && update:
+			subject.Update()
+			row.Applied = model.AuditUpdate
+			summary.Updated++
+		case row.Status == StatusDuplicate, row.Status == StatusInvalid:
+			summary.Rejected++
+		default:
+			summary.Skipped++
+		}
+	}
+	return summary
+}
@@ -0,0 +1,131 @@
+package importer
+
+import (
+	"strings"
+	"time"
+
+	"github.com/hkohlsaat/vtr/model"
+)
+
+// TeacherRow is a teacher read from a file.
+type TeacherRow struct {
+	Line    int
+	Teacher model.Teacher
+	// Existing is the teacher recorded with the same short, if there is one.
+	Existing model.Teacher
+	Status   string
+	// Message tells why the row is invalid.
+	Message string
+	// Applied is the change made applying the row, model.AuditCreate or
+	// model.AuditUpdate, or empty if nothing was changed.
+	Applied string
+}
+
+// ReadTeachers reads the teachers from a CSV or JSON file. The columns are
+// "Kürzel" (or "Short"), "Name" and "Geschlecht" ("Sex", "m" or "w") or
+// "Anrede" ("Compellation", "Herr" or "Frau"), as written by the export.
+func ReadTeachers(data []byte) ([]TeacherRow, error) {
+	records, err := readRecords(data)
+	if err != nil {
+		return nil, err
+	}
+
+	var rows []TeacherRow
+	seen := make(map[string]bool)
+	for _, r := range records {
+		teacher := model.Teacher{
+			Short: r.get("kürzel", "short"),
+			Name:  r.get("name"),
+			Sex:   strings.ToLower(r.get("geschlecht", "sex")),
+		}
+		if teacher.Sex == "" {
+			// The export writes the compellation with the name, e.g. "Herr Müller".
+			switch compellation := strings.ToLower(r.get("anrede", "compellation")); {
+			case strings.HasPrefix(compellation, "herr"):
+				teacher.Sex = "m"
+			case strings.HasPrefix(compellation, "frau"):
+				teacher.Sex = "w"
+			}
+		}
+
+		row := TeacherRow{Line: r.line, Teacher: teacher}
+		row.check(seen)
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+// check sets the status of the row. seen holds the shorts of the earlier rows.
+func (row *TeacherRow) check(seen map[string]bool) {
+	teacher := row.Teacher
+	switch {
+	case teacher.Short == "":
+		row.Status, row.Message = StatusInvalid, "Das Kürzel fehlt."
+	case teacher.Name == "":
+		row.Status, row.Message = StatusInvalid, "Der Name fehlt."
+	case teacher.Sex != "m" && teacher.Sex != "w":
+		row.Status, row.Message = StatusInvalid, "Das Geschlecht muss \"m\" oder \"w\" sein."
+	case seen[teacher.Short]:
+		row.Status, row.Message = StatusDuplicate, "Das Kürzel steht schon in einer früheren Zeile."
+	default:
+		seen[teacher.Short] = true
+		existing := model.Teacher{Short: teacher.Short}
+		if !existing.Exists() {
+			row.Status = StatusNew
+			return
+		}
+		existing.Read()
+		row.Existing = existing
+		teacher.ArchivedFrom, teacher.ArchivedUntil = existing.ArchivedFrom, existing.ArchivedUntil
+		switch {
+		case existing.ArchivedAt(time.Now()) && existing.ArchivedUntil.IsZero():
+			row.Status = StatusRestored
+		case existing == teacher:
+			row.Status = StatusUnchanged
+		default:
+			row.Status = StatusChanged
+		}
+	}
+}
+
+// ApplyTeachers creates the new teachers and, with update, updates the changed
+// and restored ones. Created teachers are removed from the unknown teachers.
+// Missing teachers are marked inactive, inactive teachers read are active
+// again and restored ones are taken out of the trash. The change made is set
+// in the rows.
+func ApplyTeachers(rows []TeacherRow, update bool) Summary {
+	var summary Summary
+	for i := range rows {
+		row := &rows[i]
+		teacher := row.Teacher
+		if row.Status == StatusRestored || row.Status == StatusChanged && row.Existing.Inactive {
+			if row.Status == StatusRestored {
+				teacher.Restore()
+			}
+			teacher.SetInactive(false)
+			row.Applied = model.AuditUpdate
+			summary.Reactivated++
+		}
+		switch {
+		case row.Status == StatusMissing:
+			teacher.SetInactive(true)
+			row.Applied = model.AuditUpdate
+			summary.Deactivated++
+		case row.Status == StatusNew && !teacher.Exists():
+			teacher.Create()
+			unknown := model.UnknownTeacher{Short: teacher.Short}
+			unknown.Delete()
+			row.Applied = model.AuditCreate
+			summary.Created++
+		case row.Status == StatusChanged && update, row.Status == StatusRestored && update, row.Status == StatusNew && update:
+			teacher.Update()
+			row.Applied = model.AuditUpdate
+			summary.Updated++
+		case row.Status == StatusDuplicate, row.Status == StatusInvalid:
+			summary.Rejected++
+		default:
+			summary.Skipped++
+		}
+	}
+	return summary
+}
@@ -10,6 +10,14 @@ import (
 )
 
 func main() {
+	// Run a command like "vtr prune" if one is given, the server otherwise.
+	if len(os.Args) > 1 {
+		if command, ok := commands[os.Args[1]]; ok {
+			command(os.Args[2:])
+			return
+		}
+	}
+
 	router := httprouter.New()
 	router.GET("/", controller.Index)
 	router.GET("/signup", controller.GetSignup)
@@ -22,26 +30,91 @@ func main() {
 	router.POST("/teachers", controller.CreateTeacher)
 	router.GET("/teachers/upload", controller.NewTeachers)
 	router.POST("/teachers/upload", controller.CreateTeachers)
+	router.POST("/teachers/upload/commit", controller.CommitTeachers)
 	router.GET("/teacher/:short", controller.GetTeacher)
 	router.GET("/teacher/:short/edit", controller.EditTeacher)
 	router.PUT("/teacher/:short", controller.UpdateTeacher)
 	router.DELETE("/teacher/:short", controller.DeleteTeacher)
+	router.POST("/teacher/:short/aliases", controller.CreateTeacherAlias)
+	router.DELETE("/teacher/:short/aliases/:alias", controller.DeleteTeacherAlias)
+	router.POST("/teacher/:short/archive", controller.ArchiveTeacher)
 
 	router.GET("/subjects", controller.GetSubjects)
 	router.GET("/subjects/new", controller.NewSubject)
 	router.POST("/subjects", controller.CreateSubject)
 	router.GET("/subjects/upload", controller.NewSubjects)
 	router.POST("/subjects/upload", controller.CreateSubjects)
+	router.POST("/subjects/upload/commit", controller.CommitSubjects)
 	router.GET("/subject/:short", controller.GetSubject)
 	router.GET("/subject/:short/edit", controller.EditSubject)
 	router.PUT("/subject/:short", controller.UpdateSubject)
 	router.DELETE("/subject/:short", controller.DeleteSubject)
+	router.POST("/subject/:short/aliases", controller.CreateSubjectAlias)
+	router.DELETE("/subject/:short/aliases/:alias", controller.DeleteSubjectAlias)
+	router.POST("/subject/:short/archive", controller.ArchiveSubject)
+
+	router.GET("/trash", controller.GetTrash)
+	router.POST("/trash/:kind/:short/restore", controller.RestoreTrash)
+	router.DELETE("/trash/:kind/:short", controller.PurgeTrash)
+	router.GET("/audit", controller.GetAudit)
+	router.POST("/audit/:id/revert", controller.RevertAudit)
+	router.POST("/undo/:token", controller.UndoChange)
 
 	router.GET("/plan", controller.GetPlan)
+	router.HEAD("/plan", controller.GetPlan)
+	router.GET("/plan.pdf", controller.GetPlanPDF)
 	router.POST("/plan", controller.PostPlan)
+	router.GET("/plan/events", controller.GetPlanEvents)
+	router.GET("/feed.atom", controller.GetFeed)
+	router.GET("/feed/:class", controller.GetClassFeed)
+
+	router.GET("/api/v1/openapi.json", controller.GetOpenAPI)
+	router.GET("/api/v1/plans", controller.GetAPIPlans)
+	router.GET("/api/v1/plans/:id", controller.GetAPIPlan)
+	router.GET("/api/v1/plans/:id/substitutions", controller.GetAPISubstitutions)
+	router.GET("/api/v1/teachers", controller.GetAPITeachers)
+	router.GET("/api/v1/teachers/:short", controller.GetAPITeacher)
+	router.GET("/api/v1/subjects", controller.GetAPISubjects)
+	router.GET("/api/v1/subjects/:short", controller.GetAPISubject)
+	router.POST("/api/v1/teachers", controller.CreateAPITeacher)
+	router.PUT("/api/v1/teachers/:short", controller.UpdateAPITeacher)
+	router.DELETE("/api/v1/teachers/:short", controller.DeleteAPITeacher)
+	router.POST("/api/v1/subjects", controller.CreateAPISubject)
+	router.PUT("/api/v1/subjects/:short", controller.UpdateAPISubject)
+	router.DELETE("/api/v1/subjects/:short", controller.DeleteAPISubject)
+	router.GET("/api/v1/unknown/teachers", controller.GetAPIUnknownTeachers)
+	router.DELETE("/api/v1/unknown/teachers/:short", controller.DeleteAPIUnknownTeacher)
+	router.GET("/api/v1/unknown/subjects", controller.GetAPIUnknownSubjects)
+	router.DELETE("/api/v1/unknown/subjects/:short", controller.DeleteAPIUnknownSubject)
+	router.GET("/unknown", controller.GetUnknown)
+	router.POST("/unknown/:kind/:short/alias", controller.AliasUnknown)
+	router.POST("/unknown/:kind/:short/ignore", controller.IgnoreUnknown)
+	router.POST("/ignorepatterns", controller.CreateIgnorePattern)
+	router.DELETE("/ignorepatterns/:id", controller.DeleteIgnorePattern)
+	router.GET("/untis", controller.GetUntis)
+	router.POST("/untis", controller.CreateUntisImport)
+	router.POST("/untis/commit", controller.CommitUntisImport)
+	router.GET("/export", controller.GetExport)
+	router.GET("/export/:dataset", controller.GetExportData)
+	router.GET("/tokens", controller.GetAPITokens)
+	router.POST("/tokens", controller.CreateAPIToken)
+	router.DELETE("/tokens/:id", controller.DeleteAPIToken)
+
+	router.GET("/vertretungsplan", controller.GetPublicPlan)
+	router.GET("/vertretungsplan/:class", controller.GetPublicClassPlan)
+	router.GET("/display", controller.GetDisplay)
+	router.GET("/displays", controller.GetDisplayProfiles)
+	router.POST("/displays", controller.SaveDisplayProfile)
+	router.DELETE("/displays/:name", controller.DeleteDisplayProfile)
+	router.GET("/plans", controller.GetPlanUploads)
+	router.GET("/plans/:id/file", controller.GetPlanUploadFile)
+	router.POST("/plans/:id/reprocess", controller.ReprocessPlanUpload)
+	router.GET("/plans/:id/compare", controller.ComparePlanUpload)
 
 	router.ServeFiles("/static/*filepath", http.Dir("static/"))
 
+	go pruneRegularly(retentionPolicy())
+
 	port := os.Args[1]
 	http.ListenAndServe(":"+port, router)
 }
@@ -0,0 +1,88 @@
+package model
+
+// Kinds of aliases.
+const (
+	AliasTeacher = "teacher"
+	AliasSubject = "subject"
+)
+
+// Alias is another short plans use for a teacher or subject.
+type Alias struct {
+	// Kind is AliasTeacher or AliasSubject.
+	Kind  string
+	Alias string
+	// Short is the short of the teacher or subject.
+	Short string
+}
+
+const alias_schema = `CREATE TABLE aliases (kind TEXT, alias TEXT, short TEXT, UNIQUE(kind, alias))`
+
+// ReadAllAliases fetches all aliases of the kind.
+func ReadAllAliases(kind string) []Alias {
+	var aliases []Alias
+	db.Select(&aliases, `SELECT kind, alias, short FROM aliases WHERE kind = ? ORDER BY alias asc`, kind)
+	return aliases
+}
+
+// ReadAliases fetches the aliases of the teacher or subject with the short.
+func ReadAliases(kind, short string) []Alias {
+	var aliases []Alias
+	db.Select(&aliases, `SELECT kind, alias, short FROM aliases WHERE kind = ? AND short = ? ORDER BY alias asc`, kind, short)
+	return aliases
+}
+
+// readAliases returns the shorts by alias for each kind.
+func readAliases() map[string]map[string]string {
+	aliases := map[string]map[string]string{AliasTeacher: {}, AliasSubject: {}}
+	for _, kind := range []string{AliasTeacher, AliasSubject} {
+		for _, a := range ReadAllAliases(kind) {
+			aliases[kind][a.Alias] = a.Short
+		}
+	}
+	return aliases
+}
+
+// Exists tells whether there is an alias of this kind with this alias.
+func (a *Alias) Exists() bool {
+	var count int
+	db.Get(&count, "SELECT count(*) FROM aliases WHERE kind = ? AND alias = ?", a.Kind, a.Alias)
+	return count > 0
+}
+
+// Create inserts this alias into the database if there isn't one with the
+// same kind and alias already.
+func (a *Alias) Create() {
+	if !a.Exists() {
+		stmt := `INSERT INTO aliases(kind, alias, short) VALUES (?, ?, ?)`
+		db.Exec(stmt, a.Kind, a.Alias, a.Short)
+	}
+}
+
+// Delete removes this alias.
+func (a *Alias) Delete() {
+	stmt := `DELETE FROM aliases WHERE kind = ? AND alias = ?`
+	db.Exec(stmt, a.Kind, a.Alias)
+}
+
+// TeacherWithAliases is a teacher with its aliases. Deletes are audited with
+// it, so undoing them brings back the aliases, too.
+type TeacherWithAliases struct {
+	Teacher
+	Aliases []Alias
+}
+
+// WithAliases returns the teacher with its aliases read from the database.
+func (t Teacher) WithAliases() TeacherWithAliases {
+	return TeacherWithAliases{Teacher: t, Aliases: ReadAliases(AliasTeacher, t.Short)}
+}
+
+// SubjectWithAliases is a subject with its aliases, see TeacherWithAliases.
+type SubjectWithAliases struct {
+	Subject
+	Aliases []Alias
+}
+
+// WithAliases returns the subject with its aliases read from the database.
+func (s Subject) WithAliases() SubjectWithAliases {
+	return SubjectWithAliases{Subject: s, Aliases: ReadAliases(AliasSubject, s.Short)}
+}
@@ -0,0 +1,52 @@
+package model
+
+import (
+	"testing"
+	"time"
+)
+
+func TestAliases(t *testing.T) {
+	teacher := Teacher{Short: "AlM", Name: "Maier", Sex: "w"}
+	teacher.Create()
+	defer teacher.Delete()
+	subject := Subject{Short: "Sp", Name: "Sport"}
+	subject.Create()
+	defer subject.Delete()
+
+	aliases := []Alias{{Kind: AliasTeacher, Alias: "AlS", Short: "AlM"}, {Kind: AliasSubject, Alias: "SP", Short: "Sp"}}
+	for _, alias := range aliases {
+		alias.Create()
+	}
+	if read := ReadAliases(AliasTeacher, "AlM"); len(read) != 1 || read[0] != aliases[0] {
+		t.Errorf("Unexpected aliases %+v.", read)
+	}
+
+	// Aliases are replaced as the plan is read.
+	day := time.Date(2016, 10, 19, 0, 0, 0, 0, time.UTC)
+	plan := &Plan{Parts: []Part{{Day: day, Substitutions: []Substitution{
+		{Class: "7a", Period: "1", SubstTeacher: Teacher{Short: "AlS"}, InstdSubject: Subject{Short: "SP"}, Text: "Aufgaben von als"},
+	}}}}
+	refine(plan)
+	s := plan.Parts[0].Substitutions[0]
+	if s.SubstTeacher.Short != "AlM" || s.InstdSubject.Short != "Sp" || len(s.TaskProviders) != 1 || s.TaskProviders[0].Short != "AlM" {
+		t.Errorf("Aliases weren't replaced: %+v", s)
+	}
+
+	// Plans read before are resolved with aliases.
+	plan.Parts[0].Substitutions[0].SubstTeacher = Teacher{Short: "AlS"}
+	plan.Resolve()
+	if s := plan.Parts[0].Substitutions[0]; s.SubstTeacher != teacher {
+		t.Errorf("Alias wasn't resolved: %+v", s.SubstTeacher)
+	}
+
+	// Aliases follow renames and are deleted with their teacher.
+	renamed := Teacher{Short: "AlN", Name: "Maier", Sex: "w"}
+	renamed.UpdateShort("AlM")
+	if read := ReadAliases(AliasTeacher, "AlN"); len(read) != 2 || read[1] != (Alias{AliasTeacher, "AlS", "AlN"}) {
+		t.Errorf("Alias didn't follow the rename: %+v", read)
+	}
+	renamed.Delete()
+	if aliases[0].Exists() {
+		t.Error("Alias wasn't deleted with its teacher.")
+	}
+}
@@ -0,0 +1,105 @@
+package model
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// annotations holds the structured information found in the free text of a
+// substitution.
+type annotations struct {
+	TaskProviders []string
+	Rooms         []string
+	MovedTo       string
+	MovedFrom     string
+}
+
+var (
+	// taskRegexp matches the notations of "Aufgaben": "Aufg.", "Aufg", "Aufgabe"
+	// and "Aufgaben".
+	taskRegexp = regexp.MustCompile(`(?i)(?:^|[^\p{L}])aufg(?:aben|abe|\.)?`)
+	// roomRegexp matches room hints like "Raum 204", "R. A12" or "Raumänd.: 1.05".
+	roomRegexp = regexp.MustCompile(`(?i)(?:^|[^\p{L}])(?:raum(?:wechsel|änderung|änd\.)?|rm\.|r\.)\s*:?\s*(?:(?:nach|in)\s+)?(\p{L}{0,3}[ -]?\d{1,3}(?:\.\d{1,3})?[a-z]?)(?:[^\p{L}\d]|$)`)
+	// moveRegexp matches references to moved lessons like "Verlegung nach Di 3. Std."
+	// or "verl. von 20.10.".
+	moveRegexp = regexp.MustCompile(`(?i)verl(?:egung|egt|\.)\s*:?\s*(nach|auf|von|vom)\s+([^;]+)`)
+	// moveEndRegexp matches the start of the next annotation after a move reference.
+	moveEndRegexp = regexp.MustCompile(`(?i),?\s*(?:aufg|raum|rm\.)`)
+)
+
+// taskFillers are the words that may stand between "Aufgaben" and the shorts
+// of the task providers.
+var taskFillers = map[string]bool{
+	"bei": true, "von": true, "vom": true, "durch": true, "für": true, "fuer": true,
+	"liegen": true, "liegt": true, "kommen": true, "kommt": true, "sind": true, "ist": true,
+}
+
+// parseText extracts annotations from the free text of a substitution. The
+// teacher function is used to validate task providers: it returns the short a
+// candidate stands for and whether the candidate is a teacher at all.
+func parseText(text string, teacher func(string) (string, bool)) annotations {
+	var a annotations
+
+	for _, loc := range taskRegexp.FindAllStringIndex(text, -1) {
+		for _, short := range readTaskProviders(text[loc[1]:], teacher) {
+			if !contains(a.TaskProviders, short) {
+				a.TaskProviders = append(a.TaskProviders, short)
+			}
+		}
+	}
+
+	for _, match := range roomRegexp.FindAllStringSubmatch(text, -1) {
+		room := strings.TrimSpace(match[1])
+		if !contains(a.Rooms, room) {
+			a.Rooms = append(a.Rooms, room)
+		}
+	}
+
+	for _, match := range moveRegexp.FindAllStringSubmatch(text, -1) {
+		reference := match[2]
+		if loc := moveEndRegexp.FindStringIndex(reference); loc != nil {
+			reference = reference[:loc[0]]
+		}
+		reference = strings.TrimRight(strings.TrimSpace(reference), ",")
+		switch strings.ToLower(match[1]) {
+		case "nach", "auf":
+			a.MovedTo = reference
+		default:
+			a.MovedFrom = reference
+		}
+	}
+
+	return a
+}
+
+// readTaskProviders reads the list of task providers at the beginning of s,
+// like "MÜL", "bei MÜL" or "MÜL/SCH und Md".
+func readTaskProviders(s string, teacher func(string) (string, bool)) []string {
+	var shorts []string
+	words := strings.FieldsFunc(s, func(r rune) bool {
+		return unicode.IsSpace(r) || strings.ContainsRune(":-/,+&", r)
+	})
+	for _, word := range words {
+		word = strings.TrimRight(word, ".;)")
+		if short, ok := teacher(word); ok {
+			shorts = append(shorts, short)
+		} else if len(shorts) == 0 && taskFillers[strings.ToLower(word)] {
+			continue
+		} else if len(shorts) > 0 && strings.ToLower(word) == "und" {
+			continue
+		} else {
+			break
+		}
+	}
+	return shorts
+}
+
+func contains(list []string, s string) bool {
+	for _, e := range list {
+		if e == s {
+			return true
+		}
+	}
+	return false
+}
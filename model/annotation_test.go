@@ -0,0 +1,49 @@
+package model
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// Texts as they appear in our plans.
+var annotationCorpus = []struct {
+	text     string
+	expected annotations
+}{
+	{"Aufg. MÜL", annotations{TaskProviders: []string{"MÜL"}}},
+	{"Aufgabe MÜL", annotations{TaskProviders: []string{"MÜL"}}},
+	{"Aufgaben bei MÜL", annotations{TaskProviders: []string{"MÜL"}}},
+	{"Aufgaben liegen bei Md", annotations{TaskProviders: []string{"Md"}}},
+	{"Aufg. MÜL/SCH", annotations{TaskProviders: []string{"MÜL", "SCH"}}},
+	{"Aufg. von MÜL und Wö", annotations{TaskProviders: []string{"MÜL", "Wö"}}},
+	{"Aufg.: md", annotations{TaskProviders: []string{"Md"}}},
+	{"Aufg. Sz (S. 34)", annotations{TaskProviders: []string{"Sz"}}},
+	{"Aufg. im Sekretariat", annotations{}},
+	{"Aufgaben XYZ", annotations{}},
+	{"Raum 204", annotations{Rooms: []string{"204"}}},
+	{"Aufg. Sz, Raum 204", annotations{TaskProviders: []string{"Sz"}, Rooms: []string{"204"}}},
+	{"Raumänderung: A12", annotations{Rooms: []string{"A12"}}},
+	{"in R. 1.05 statt PH2", annotations{Rooms: []string{"1.05"}}},
+	{"Klassenraum", annotations{}},
+	{"Verlegung nach Di 3. Std.", annotations{MovedTo: "Di 3. Std."}},
+	{"verlegt auf 20.10., 5. Std.; Aufg. MÜL", annotations{TaskProviders: []string{"MÜL"}, MovedTo: "20.10., 5. Std."}},
+	{"Verl. von Mo 1. Std., Raum 105", annotations{Rooms: []string{"105"}, MovedFrom: "Mo 1. Std."}},
+	{"Vertretung", annotations{}},
+	{"", annotations{}},
+}
+
+func TestParseText(t *testing.T) {
+	shorts := map[string]string{"mül": "MÜL", "sch": "SCH", "md": "Md", "wö": "Wö", "sz": "Sz"}
+	teacher := func(candidate string) (string, bool) {
+		short, ok := shorts[strings.ToLower(candidate)]
+		return short, ok
+	}
+
+	for _, test := range annotationCorpus {
+		actual := parseText(test.text, teacher)
+		if !reflect.DeepEqual(actual, test.expected) {
+			t.Errorf("Parsed %q as %+v, expected %+v.", test.text, actual, test.expected)
+		}
+	}
+}
@@ -0,0 +1,50 @@
+package model
+
+import "time"
+
+// archivedAt tells whether a teacher or subject archived from until the given
+// times is in the trash at the time at.
+func archivedAt(from, until, at time.Time) bool {
+	return !from.IsZero() && !at.Before(from) && (until.IsZero() || at.Before(until))
+}
+
+// archive sets the time range the teacher or subject with the short is in the
+// trash. Zero times are stored as NULL.
+func archive(table, short string, from, until time.Time) {
+	nullable := func(t time.Time) interface{} {
+		if t.IsZero() {
+			return nil
+		}
+		return t
+	}
+	db.Exec(`UPDATE `+table+` SET archived_from = ?, archived_until = ? WHERE short = ?`,
+		nullable(from), nullable(until), short)
+}
+
+// PurgeArchived removes the teachers and subjects archived before the given
+// time without a time they return, see Teacher.Archive. With dryRun nothing
+// is removed. The shorts of the teachers and subjects are returned. Removals
+// are audited as done by AuditSystem.
+func PurgeArchived(before time.Time, dryRun bool) (teachers, subjects []string) {
+	for _, t := range readTeachers() {
+		if !t.ArchivedFrom.IsZero() && t.ArchivedFrom.Before(before) && t.ArchivedUntil.IsZero() {
+			teachers = append(teachers, t.Short)
+			if !dryRun {
+				deleted := t.WithAliases()
+				t.Delete()
+				Audit(AuditSystem, AuditDelete, AuditTeacher, t.Short, deleted, nil)
+			}
+		}
+	}
+	for _, s := range readSubjects() {
+		if !s.ArchivedFrom.IsZero() && s.ArchivedFrom.Before(before) && s.ArchivedUntil.IsZero() {
+			subjects = append(subjects, s.Short)
+			if !dryRun {
+				deleted := s.WithAliases()
+				s.Delete()
+				Audit(AuditSystem, AuditDelete, AuditSubject, s.Short, deleted, nil)
+			}
+		}
+	}
+	return teachers, subjects
+}
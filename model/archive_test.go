@@ -0,0 +1,90 @@
+package model
+
+import (
+	"testing"
+	"time"
+)
+
+func TestArchive(t *testing.T) {
+	now := time.Now()
+	left := Teacher{Short: "ArA", Name: "Weg", Sex: "m"}
+	left.Create()
+	defer left.Delete()
+	leave := Teacher{Short: "ArB", Name: "Elternzeit", Sex: "w"}
+	leave.Create()
+	defer leave.Delete()
+	subject := Subject{Short: "ArS", Name: "Latein"}
+	subject.Create()
+	defer subject.Delete()
+
+	left.Archive(now.Add(-time.Hour), time.Time{})
+	leave.Archive(now.Add(-time.Hour), now.Add(time.Hour))
+	subject.Archive(now.Add(-time.Hour), time.Time{})
+
+	for _, teacher := range ReadAllTeachers() {
+		if teacher.Short == left.Short || teacher.Short == leave.Short {
+			t.Errorf("Archived teacher %s was read.", teacher.Short)
+		}
+	}
+	if archived := ReadArchivedTeachers(); !containsTeacher(archived, left.Short) || !containsTeacher(archived, leave.Short) {
+		t.Errorf("Archived teachers weren't read: %+v", archived)
+	}
+	teachers, subjects := readDirectory()
+	if _, ok := teachers[left.Short]; !ok {
+		t.Error("Archived teacher isn't resolved.")
+	}
+	if _, ok := subjects[subject.Short]; !ok {
+		t.Error("Archived subject isn't resolved.")
+	}
+	if leave.ArchivedAt(now.Add(2 * time.Hour)) {
+		t.Error("Teacher is archived after the end of the range.")
+	}
+
+	// Only teachers and subjects without end are purged.
+	purgedTeachers, purgedSubjects := PurgeArchived(now, true)
+	if !containsShort(purgedTeachers, left.Short) || containsShort(purgedTeachers, leave.Short) || !containsShort(purgedSubjects, subject.Short) {
+		t.Errorf("Unexpected purge %v, %v.", purgedTeachers, purgedSubjects)
+	}
+	if !left.Exists() {
+		t.Error("Teacher was purged in a dry run.")
+	}
+	if purgedTeachers, _ = PurgeArchived(now.Add(-2*time.Hour), false); containsShort(purgedTeachers, left.Short) {
+		t.Error("Teacher was purged before the retention ended.")
+	}
+
+	leave.Restore()
+	leave.Read()
+	if !leave.ArchivedFrom.IsZero() || !containsTeacher(ReadAllTeachers(), leave.Short) {
+		t.Errorf("Teacher wasn't restored: %+v", leave)
+	}
+
+	version := DirectoryVersion()
+	PurgeArchived(now, false)
+	if left.Exists() || subject.Exists() {
+		t.Error("Archived teacher or subject wasn't purged.")
+	}
+	if DirectoryVersion() == version {
+		t.Error("Directory version didn't change.")
+	}
+	if teachers, _ := readDirectory(); teachers[left.Short].Short != "" {
+		t.Error("Purged teacher is still resolved.")
+	}
+}
+
+func containsTeacher(teachers []Teacher, short string) bool {
+	for _, teacher := range teachers {
+		if teacher.Short == short {
+			return true
+		}
+	}
+	return false
+}
+
+func containsShort(shorts []string, short string) bool {
+	for _, s := range shorts {
+		if s == short {
+			return true
+		}
+	}
+	return false
+}
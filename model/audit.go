@@ -0,0 +1,144 @@
+package model
+
+import (
+	"encoding/json"
+	"strings"
+	"time"
+)
+
+// Actions recorded in the audit log.
+const (
+	AuditCreate  = "create"
+	AuditUpdate  = "update"
+	AuditDelete  = "delete"
+	AuditArchive = "archive"
+	AuditRestore = "restore"
+	AuditUpload  = "upload"
+)
+
+// Entities recorded in the audit log.
+const (
+	AuditTeacher        = "teacher"
+	AuditSubject        = "subject"
+	AuditAlias          = "alias"
+	AuditUser           = "user"
+	AuditPlan           = "plan"
+	AuditToken          = "token"
+	AuditDisplay        = "display"
+	AuditUnknownTeacher = "unknown-teacher"
+	AuditUnknownSubject = "unknown-subject"
+	AuditIgnorePattern  = "ignorepattern"
+)
+
+// AuditSystem is the actor of changes the program makes itself, e.g. when
+// pruning.
+const AuditSystem = "system"
+
+// AuditEntry records who changed what when.
+type AuditEntry struct {
+	ID     int64
+	Time   time.Time
+	Actor  string
+	Action string
+	Entity string
+	// Key identifies the changed entity, e.g. the teacher's short.
+	Key string
+	// Before and After hold the entity before and after the change encoded
+	// as JSON. They are empty if the entity didn't exist.
+	Before string `db:"before_json"`
+	After  string `db:"after_json"`
+}
+
+// The audit log is append-only, entries can't be changed or deleted.
+const audit_schema = `CREATE TABLE audit_log (id INTEGER PRIMARY KEY, time DATETIME, actor TEXT, action TEXT,
+	entity TEXT, key TEXT, before_json TEXT, after_json TEXT);
+CREATE INDEX audit_log_time ON audit_log (time);
+CREATE TRIGGER audit_log_update BEFORE UPDATE ON audit_log BEGIN SELECT RAISE(ABORT, 'audit log is append-only'); END;
+CREATE TRIGGER audit_log_delete BEFORE DELETE ON audit_log BEGIN SELECT RAISE(ABORT, 'audit log is append-only'); END`
+
+// Audit appends an entry to the audit log and returns its ID. before and after
+// are encoded as JSON, nil is recorded as empty.
+func Audit(actor, action, entity, key string, before, after interface{}) int64 {
+	encode := func(value interface{}) string {
+		if value == nil {
+			return ""
+		}
+		encoded, _ := json.Marshal(value)
+		return string(encoded)
+	}
+	// Times are stored in UTC to be compared as text.
+	result, err := db.Exec(`INSERT INTO audit_log (time, actor, action, entity, key, before_json, after_json) VALUES (?, ?, ?, ?, ?, ?, ?)`,
+		time.Now().UTC(), actor, action, entity, key, encode(before), encode(after))
+	if err != nil {
+		return 0
+	}
+	id, _ := result.LastInsertId()
+	return id
+}
+
+// Read completes this entry with the entry with this entry's ID. It tells
+// whether there is one.
+func (e *AuditEntry) Read() bool {
+	err := db.Get(e, `SELECT id, time, actor, action, entity, key, before_json, after_json FROM audit_log WHERE id = ?`, e.ID)
+	e.Time = e.Time.Local()
+	return err == nil
+}
+
+// AuditFilter selects audit entries. Empty fields select all entries.
+type AuditFilter struct {
+	Actor  string
+	Action string
+	Entity string
+	Key    string
+	// From and To limit the time of the entries, To excluded.
+	From time.Time
+	To   time.Time
+	// Limit is the maximum number of entries read, 0 reads all.
+	Limit int
+}
+
+// ReadAudit returns the audit entries selected by the filter, newest first.
+func ReadAudit(filter AuditFilter) []AuditEntry {
+	var conditions []string
+	var args []interface{}
+	for _, c := range []struct {
+		column, value string
+	}{{"actor", filter.Actor}, {"action", filter.Action}, {"entity", filter.Entity}, {"key", filter.Key}} {
+		if c.value != "" {
+			conditions = append(conditions, c.column+" = ?")
+			args = append(args, c.value)
+		}
+	}
+	if !filter.From.IsZero() {
+		conditions = append(conditions, "time >= ?")
+		args = append(args, filter.From.UTC())
+	}
+	if !filter.To.IsZero() {
+		conditions = append(conditions, "time < ?")
+		args = append(args, filter.To.UTC())
+	}
+
+	query := `SELECT id, time, actor, action, entity, key, before_json, after_json FROM audit_log`
+	if len(conditions) > 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
+	}
+	query += " ORDER BY id DESC"
+	if filter.Limit > 0 {
+		query += " LIMIT ?"
+		args = append(args, filter.Limit)
+	}
+
+	var entries []AuditEntry
+	db.Select(&entries, query, args...)
+	for i := range entries {
+		entries[i].Time = entries[i].Time.Local()
+	}
+	return entries
+}
+
+// ReadAuditActors returns the actors found in the audit log.
+func ReadAuditActors() []string {
+	var actors []string
+	db.Select(&actors, `SELECT DISTINCT actor FROM audit_log ORDER BY actor`)
+	return actors
+}
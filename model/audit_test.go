@@ -0,0 +1,44 @@
+package model
+
+import (
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestAudit(t *testing.T) {
+	start := time.Now().Add(-time.Second)
+	// The log is kept between runs, so each run audits as its own actor.
+	actor := "audittest-" + strconv.FormatInt(time.Now().UnixNano(), 10)
+	before := Teacher{Short: "AuA", Name: "Alt", Sex: "m"}
+	after := Teacher{Short: "AuA", Name: "Neu", Sex: "m"}
+	Audit(actor, AuditCreate, AuditTeacher, "AuA", nil, before)
+	Audit(actor, AuditUpdate, AuditTeacher, "AuA", before, after)
+	Audit(actor, AuditCreate, AuditSubject, "AuS", nil, Subject{Short: "AuS"})
+
+	entries := ReadAudit(AuditFilter{Actor: actor, Entity: AuditTeacher})
+	if len(entries) != 2 {
+		t.Fatalf("%d entries read, expected 2.", len(entries))
+	}
+	if entries[0].Action != AuditUpdate || entries[0].Key != "AuA" || entries[1].Before != "" {
+		t.Errorf("Unexpected entries %+v.", entries)
+	}
+	if entries[0].Before == "" || entries[0].After == "" || entries[0].Time.Before(start) {
+		t.Errorf("Values or time weren't recorded: %+v", entries[0])
+	}
+
+	if entries := ReadAudit(AuditFilter{Actor: actor, From: time.Now().Add(time.Minute)}); len(entries) != 0 {
+		t.Errorf("Entries after now were read: %+v", entries)
+	}
+	if entries := ReadAudit(AuditFilter{Actor: actor, From: start, Limit: 1}); len(entries) != 1 || entries[0].Entity != AuditSubject {
+		t.Errorf("Unexpected limited entries %+v.", entries)
+	}
+
+	// The log is append-only.
+	if _, err := db.Exec(`DELETE FROM audit_log WHERE actor = ?`, actor); err == nil {
+		t.Error("Audit entries were deleted.")
+	}
+	if _, err := db.Exec(`UPDATE audit_log SET actor = ? WHERE actor = ?`, "other", actor); err == nil {
+		t.Error("Audit entries were changed.")
+	}
+}
@@ -0,0 +1,90 @@
+package model
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Classes returns the classes the substitution is for. Untis lists several
+// classes separated by commas, e.g. "5a, 5b".
+func (s Substitution) Classes() []string {
+	var classes []string
+	for _, class := range strings.Split(s.Class, ",") {
+		if class = strings.TrimSpace(class); class != "" {
+			classes = append(classes, class)
+		}
+	}
+	return classes
+}
+
+// HasClass tells whether the substitution is for the class.
+func (s Substitution) HasClass(class string) bool {
+	for _, c := range s.Classes() {
+		if strings.EqualFold(c, class) {
+			return true
+		}
+	}
+	return false
+}
+
+// Classes returns all classes having substitutions in this plan in the
+// order of their grades.
+func (plan *Plan) Classes() []string {
+	seen := make(map[string]bool)
+	var classes []string
+	for _, part := range plan.Parts {
+		for _, s := range part.Substitutions {
+			for _, class := range s.Classes() {
+				if !seen[class] {
+					seen[class] = true
+					classes = append(classes, class)
+				}
+			}
+		}
+	}
+	SortClasses(classes)
+	return classes
+}
+
+// ForClass returns a copy of the plan only holding the substitutions for the class.
+func (plan *Plan) ForClass(class string) *Plan {
+	filtered := &Plan{Created: plan.Created, Encoding: plan.Encoding}
+	for _, part := range plan.Parts {
+		p := Part{Day: part.Day, Substitutions: []Substitution{}}
+		for _, s := range part.Substitutions {
+			if s.HasClass(class) {
+				p.Substitutions = append(p.Substitutions, s)
+			}
+		}
+		filtered.Parts = append(filtered.Parts, p)
+	}
+	return filtered
+}
+
+// SortClasses sorts classes by grade first, so "5a" comes before "10a".
+func SortClasses(classes []string) {
+	sort.SliceStable(classes, func(i, j int) bool {
+		gi, ri := splitGrade(classes[i])
+		gj, rj := splitGrade(classes[j])
+		if gi != gj {
+			return gi < gj
+		}
+		return ri < rj
+	})
+}
+
+// splitGrade splits a class like "10a" into its grade 10 and the rest "a".
+// Classes without grade, like "Q1", are sorted after all others.
+func splitGrade(class string) (int, string) {
+	end := strings.IndexFunc(class, func(r rune) bool { return !unicode.IsDigit(r) })
+	if end == -1 {
+		end = len(class)
+	}
+	grade, err := strconv.Atoi(class[:end])
+	if err != nil {
+		return 1 << 30, class
+	}
+	return grade, class[end:]
+}
@@ -0,0 +1,31 @@
+package model
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSortClasses(t *testing.T) {
+	classes := []string{"10a", "Q1", "5b", "5a", "7c"}
+	SortClasses(classes)
+	expected := []string{"5a", "5b", "7c", "10a", "Q1"}
+	if !reflect.DeepEqual(classes, expected) {
+		t.Errorf("Sorted %v, expected %v.", classes, expected)
+	}
+}
+
+func TestPlanForClass(t *testing.T) {
+	plan := &Plan{Parts: []Part{Part{Substitutions: []Substitution{
+		Substitution{Class: "5a, 5b", Period: "1"},
+		Substitution{Class: "5b", Period: "2"},
+		Substitution{Class: "6a", Period: "3"}}}}}
+
+	if classes := plan.Classes(); !reflect.DeepEqual(classes, []string{"5a", "5b", "6a"}) {
+		t.Errorf("Unexpected classes: %v", classes)
+	}
+
+	substitutions := plan.ForClass("5B").Parts[0].Substitutions
+	if len(substitutions) != 2 || substitutions[0].Period != "1" || substitutions[1].Period != "2" {
+		t.Errorf("Unexpected substitutions for 5b: %+v", substitutions)
+	}
+}
@@ -0,0 +1,79 @@
+package model
+
+import "time"
+
+// Kinds of changes.
+const (
+	ChangeAdded   = "added"
+	ChangeRemoved = "removed"
+	ChangeChanged = "changed"
+)
+
+// Change describes how a substitution differs between two plans. Before is
+// nil for added substitutions, After is nil for removed ones.
+type Change struct {
+	Day    time.Time
+	Before *Substitution
+	After  *Substitution
+}
+
+// Kind tells whether the substitution was added, removed or changed.
+func (c Change) Kind() string {
+	switch {
+	case c.Before == nil:
+		return ChangeAdded
+	case c.After == nil:
+		return ChangeRemoved
+	default:
+		return ChangeChanged
+	}
+}
+
+// Substitution returns the current state of the substitution, or the last
+// one if it was removed.
+func (c Change) Substitution() *Substitution {
+	if c.After != nil {
+		return c.After
+	}
+	return c.Before
+}
+
+// Compare lists the changes from the old to the new plan. Substitutions are
+// matched by their ID. The substitutions of days new to the plan are added,
+// but days dropping out of the plan are left out: plans move forward day by
+// day and a past day doesn't mean its substitutions were cancelled.
+func Compare(old, new *Plan) []Change {
+	oldParts := make(map[string]Part)
+	for _, part := range old.Parts {
+		oldParts[part.Day.Format("2006-01-02")] = part
+	}
+
+	var changes []Change
+	for _, part := range new.Parts {
+		oldPart := oldParts[part.Day.Format("2006-01-02")]
+		before := make(map[string]*Substitution)
+		for i := range oldPart.Substitutions {
+			before[oldPart.Substitutions[i].ID] = &oldPart.Substitutions[i]
+		}
+
+		for i := range part.Substitutions {
+			after := &part.Substitutions[i]
+			b, ok := before[after.ID]
+			if !ok {
+				changes = append(changes, Change{Day: part.Day, After: after})
+				continue
+			}
+			delete(before, after.ID)
+			if b.content() != after.content() {
+				changes = append(changes, Change{Day: part.Day, Before: b, After: after})
+			}
+		}
+
+		for i := range oldPart.Substitutions {
+			if b := &oldPart.Substitutions[i]; before[b.ID] != nil {
+				changes = append(changes, Change{Day: part.Day, Before: b})
+			}
+		}
+	}
+	return changes
+}
@@ -25,6 +25,54 @@ func init() {
 		db.MustExec(plan_schema)
 		db.MustExec(unknown_schema)
 	}
+	if !tables["display_profiles"] {
+		db.MustExec(display_profile_schema)
+	}
+	if !tables["api_tokens"] {
+		db.MustExec(api_token_schema)
+	}
+	if !tables["aliases"] {
+		db.MustExec(alias_schema)
+	}
+	if !tables["renames"] {
+		db.MustExec(rename_schema)
+	}
+	if !tables["plan_version"] {
+		db.MustExec(plan_version_schema)
+	}
+	if !tables["directory_version"] {
+		db.MustExec(directory_version_schema)
+	}
+	if !tables["audit_log"] {
+		db.MustExec(audit_schema)
+	}
+	if !tables["ignore_patterns"] {
+		db.MustExec(ignore_pattern_schema)
+		for _, pattern := range defaultIgnorePatterns {
+			db.MustExec(`INSERT INTO ignore_patterns(pattern) VALUES (?)`, pattern)
+		}
+	}
+
+	// Add columns introduced after the tables were created.
+	addColumn("plans", "encoding", "TEXT")
+	addColumn("plans", "hash", "TEXT")
+	addColumn("plans", "confirmed", "DATETIME")
+	addColumn("plans", "previous_json", "TEXT")
+	addColumn("plans", "reprocessed_at", "DATETIME")
+	addColumn("teachers", "inactive", "BOOLEAN NOT NULL DEFAULT 0")
+	for _, table := range []string{"teachers", "subjects"} {
+		addColumn(table, "archived_from", "DATETIME")
+		addColumn(table, "archived_until", "DATETIME")
+	}
+	for _, column := range unknownColumns {
+		addColumn("unknown_teachers", column[0], column[1])
+		addColumn("unknown_subjects", column[0], column[1])
+	}
+
+	if !hasColumn("plans", "id") {
+		migratePlanIDs()
+	}
+	db.MustExec(plan_version_triggers)
 }
 
 func tables() map[string]bool {
@@ -40,3 +88,22 @@ func tables() map[string]bool {
 
 	return used
 }
+
+// addColumn adds the column to the table if the table doesn't have it yet.
+func addColumn(table, column, definition string) {
+	if !hasColumn(table, column) {
+		db.MustExec(`ALTER TABLE ` + table + ` ADD COLUMN ` + column + ` ` + definition)
+	}
+}
+
+// hasColumn tells whether the table has the column.
+func hasColumn(table, column string) bool {
+	var names []string
+	db.Select(&names, `SELECT name FROM pragma_table_info(?)`, table)
+	for _, name := range names {
+		if name == column {
+			return true
+		}
+	}
+	return false
+}
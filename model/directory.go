@@ -0,0 +1,118 @@
+package model
+
+import (
+	"database/sql"
+	"sync"
+	"time"
+)
+
+// directory_version_schema counts the changes of the teachers, subjects and
+// aliases in the database, so changes made by another process, e.g. the
+// prune command, are noticed.
+const directory_version_schema = `CREATE TABLE directory_version (version INTEGER NOT NULL, changed DATETIME);
+INSERT INTO directory_version (version) VALUES (0);
+CREATE TRIGGER teachers_insert AFTER INSERT ON teachers BEGIN UPDATE directory_version SET version = version + 1, changed = strftime('%Y-%m-%d %H:%M:%f', 'now'); END;
+CREATE TRIGGER teachers_update AFTER UPDATE ON teachers BEGIN UPDATE directory_version SET version = version + 1, changed = strftime('%Y-%m-%d %H:%M:%f', 'now'); END;
+CREATE TRIGGER teachers_delete AFTER DELETE ON teachers BEGIN UPDATE directory_version SET version = version + 1, changed = strftime('%Y-%m-%d %H:%M:%f', 'now'); END;
+CREATE TRIGGER subjects_insert AFTER INSERT ON subjects BEGIN UPDATE directory_version SET version = version + 1, changed = strftime('%Y-%m-%d %H:%M:%f', 'now'); END;
+CREATE TRIGGER subjects_update AFTER UPDATE ON subjects BEGIN UPDATE directory_version SET version = version + 1, changed = strftime('%Y-%m-%d %H:%M:%f', 'now'); END;
+CREATE TRIGGER subjects_delete AFTER DELETE ON subjects BEGIN UPDATE directory_version SET version = version + 1, changed = strftime('%Y-%m-%d %H:%M:%f', 'now'); END;
+CREATE TRIGGER aliases_insert AFTER INSERT ON aliases BEGIN UPDATE directory_version SET version = version + 1, changed = strftime('%Y-%m-%d %H:%M:%f', 'now'); END;
+CREATE TRIGGER aliases_update AFTER UPDATE ON aliases BEGIN UPDATE directory_version SET version = version + 1, changed = strftime('%Y-%m-%d %H:%M:%f', 'now'); END;
+CREATE TRIGGER aliases_delete AFTER DELETE ON aliases BEGIN UPDATE directory_version SET version = version + 1, changed = strftime('%Y-%m-%d %H:%M:%f', 'now'); END`
+
+// directory caches all teachers and subjects by their shorts to resolve the
+// shorts in plans. It is read again whenever the directory version changes.
+var directory struct {
+	sync.Mutex
+	version  int
+	teachers map[string]Teacher
+	subjects map[string]Subject
+	// aliases holds the shorts by alias for each kind of alias.
+	aliases map[string]map[string]string
+}
+
+// DirectoryVersion returns a number that changes whenever a teacher, subject
+// or alias is changed, also by another process.
+func DirectoryVersion() int {
+	var version int
+	db.Get(&version, `SELECT version FROM directory_version`)
+	return version
+}
+
+// DirectoryChanged returns the time a teacher, subject or alias was last
+// changed. It is zero if they never changed.
+func DirectoryChanged() time.Time {
+	var changed sql.NullTime
+	db.Get(&changed, `SELECT changed FROM directory_version`)
+	return changed.Time
+}
+
+// readDirectory returns the teachers and subjects by their shorts and aliases,
+// reading them from the database if they aren't cached. Archived teachers and
+// subjects are included, so they are still resolved in plans.
+func readDirectory() (map[string]Teacher, map[string]Subject) {
+	version := DirectoryVersion()
+
+	directory.Lock()
+	defer directory.Unlock()
+
+	if directory.teachers == nil || directory.version != version {
+		directory.version = version
+		directory.aliases = readAliases()
+		directory.teachers = make(map[string]Teacher)
+		for _, t := range readTeachers() {
+			directory.teachers[t.Short] = t
+		}
+		directory.subjects = make(map[string]Subject)
+		for _, s := range readSubjects() {
+			directory.subjects[s.Short] = s
+		}
+		for alias, short := range directory.aliases[AliasTeacher] {
+			if t, ok := directory.teachers[short]; ok {
+				if _, taken := directory.teachers[alias]; !taken {
+					directory.teachers[alias] = t
+				}
+			}
+		}
+		for alias, short := range directory.aliases[AliasSubject] {
+			if s, ok := directory.subjects[short]; ok {
+				if _, taken := directory.subjects[alias]; !taken {
+					directory.subjects[alias] = s
+				}
+			}
+		}
+	}
+	return directory.teachers, directory.subjects
+}
+
+// Resolve completes the teachers and subjects of the plan with the current
+// information recorded for their shorts. Aliases are replaced by the shorts
+// they stand for. Shorts unknown by now are left without names.
+func (plan *Plan) Resolve() {
+	teachers, subjects := readDirectory()
+	teacher := func(t *Teacher) {
+		if known, ok := teachers[t.Short]; ok {
+			*t = known
+		} else {
+			*t = Teacher{Short: t.Short}
+		}
+	}
+
+	for p := range plan.Parts {
+		for i := range plan.Parts[p].Substitutions {
+			s := &plan.Parts[p].Substitutions[i]
+			teacher(&s.SubstTeacher)
+			teacher(&s.InstdTeacher)
+			teacher(&s.TaskProvider)
+			for j := range s.TaskProviders {
+				teacher(&s.TaskProviders[j])
+			}
+			if known, ok := subjects[s.InstdSubject.Short]; ok {
+				s.InstdSubject = known
+			} else {
+				s.InstdSubject = Subject{Short: s.InstdSubject.Short}
+			}
+		}
+	}
+}
@@ -0,0 +1,33 @@
+package model
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLastPlanResolve(t *testing.T) {
+	day := time.Date(2016, 10, 21, 0, 0, 0, 0, time.UTC)
+	plan := &Plan{Created: day, Parts: []Part{Part{Day: day, Substitutions: []Substitution{
+		Substitution{Class: "8d", Period: "4", SubstTeacher: Teacher{Short: "Rs"},
+			InstdSubject: Subject{Short: "Ph"}}}}}}
+	plan.Parts[0].identify()
+	plan.Create(nil)
+
+	substitution := LastPlan().Parts[0].Substitutions[0]
+	if substitution.SubstTeacher.Name != "" || substitution.InstdSubject.Name != "" {
+		t.Errorf("Unknown shorts were resolved: %+v", substitution)
+	}
+
+	// Teachers and subjects added later are shown in the plan served.
+	teacher := Teacher{Short: "Rs", Name: "Rast", Sex: "w"}
+	teacher.Create()
+	defer teacher.Delete()
+	subject := Subject{Short: "Ph", Name: "Physik"}
+	subject.Create()
+	defer subject.Delete()
+
+	substitution = LastPlan().Parts[0].Substitutions[0]
+	if substitution.SubstTeacher != teacher || substitution.InstdSubject != subject {
+		t.Errorf("Plan wasn't resolved with new teacher and subject: %+v", substitution)
+	}
+}
@@ -0,0 +1,58 @@
+package model
+
+// DisplayProfile holds the layout of a signage display showing the plan.
+type DisplayProfile struct {
+	Name string
+	// Columns is the number of columns the substitutions are shown in.
+	Columns int
+	// FontSize is the font size in pixels.
+	FontSize int
+	// Duration is the number of seconds each page is shown.
+	Duration int
+}
+
+// DefaultDisplayProfile is the layout used for settings missing in a profile.
+var DefaultDisplayProfile = DisplayProfile{Columns: 2, FontSize: 24, Duration: 15}
+
+const display_profile_schema = `CREATE TABLE display_profiles (name TEXT UNIQUE, columns INTEGER, fontsize INTEGER, duration INTEGER)`
+
+// ReadAllDisplayProfiles fetches all display profiles from the database.
+func ReadAllDisplayProfiles() []DisplayProfile {
+	var profiles []DisplayProfile
+	db.Select(&profiles, `SELECT name, columns, fontsize, duration FROM display_profiles ORDER BY name asc`)
+
+	return profiles
+}
+
+// Exists tells whether there is a display profile with this profile's name.
+func (p *DisplayProfile) Exists() bool {
+	var count int
+	db.Get(&count, "SELECT count(*) FROM display_profiles WHERE name = ?", p.Name)
+	return count > 0
+}
+
+// Create inserts this display profile into the database if there isn't a
+// profile with this name already.
+func (p *DisplayProfile) Create() {
+	if !p.Exists() {
+		stmt := `INSERT INTO display_profiles(name, columns, fontsize, duration) VALUES (?, ?, ?, ?)`
+		db.Exec(stmt, p.Name, p.Columns, p.FontSize, p.Duration)
+	}
+}
+
+// Read completes this display profile with the settings saved for its name.
+func (p *DisplayProfile) Read() {
+	db.Get(p, "SELECT name, columns, fontsize, duration FROM display_profiles WHERE name = ?", p.Name)
+}
+
+// Update saves the settings of this display profile.
+func (p *DisplayProfile) Update() {
+	stmt := `UPDATE display_profiles SET columns = ?, fontsize = ?, duration = ? WHERE name = ?`
+	db.Exec(stmt, p.Columns, p.FontSize, p.Duration, p.Name)
+}
+
+// Delete removes this display profile from the database.
+func (p *DisplayProfile) Delete() {
+	stmt := `DELETE FROM display_profiles WHERE name = ?`
+	db.Exec(stmt, p.Name)
+}
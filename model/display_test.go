@@ -0,0 +1,24 @@
+package model
+
+import "testing"
+
+func TestDisplayProfile(t *testing.T) {
+	profile := DisplayProfile{Name: "Eingang", Columns: 3, FontSize: 30, Duration: 10}
+	profile.Create()
+	if !profile.Exists() {
+		t.Fatal("Display profile wasn't created.")
+	}
+
+	profile.Columns = 1
+	profile.Update()
+	read := DisplayProfile{Name: profile.Name}
+	read.Read()
+	if read != profile {
+		t.Errorf("Display profile read as %+v, expected %+v.", read, profile)
+	}
+
+	profile.Delete()
+	if profile.Exists() {
+		t.Error("Display profile still exists after deletion.")
+	}
+}
@@ -0,0 +1,109 @@
+package model
+
+import (
+	"bytes"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/charmap"
+	"golang.org/x/text/encoding/unicode"
+)
+
+// Names of the encodings plan uploads may be written in.
+const (
+	EncodingUTF8        = "UTF-8"
+	EncodingISO8859_1   = "ISO-8859-1"
+	EncodingWindows1252 = "Windows-1252"
+)
+
+var encodings = map[string]encoding.Encoding{
+	EncodingUTF8:        unicode.UTF8,
+	EncodingISO8859_1:   charmap.ISO8859_1,
+	EncodingWindows1252: charmap.Windows1252,
+}
+
+// encodingLabels maps the labels found in charset declarations to the
+// encodings' names.
+var encodingLabels = map[string]string{
+	"utf-8":        EncodingUTF8,
+	"utf8":         EncodingUTF8,
+	"iso-8859-1":   EncodingISO8859_1,
+	"iso8859-1":    EncodingISO8859_1,
+	"iso_8859-1":   EncodingISO8859_1,
+	"latin1":       EncodingISO8859_1,
+	"l1":           EncodingISO8859_1,
+	"windows-1252": EncodingWindows1252,
+	"cp1252":       EncodingWindows1252,
+	"x-cp1252":     EncodingWindows1252,
+}
+
+var (
+	utf8BOM = []byte{0xEF, 0xBB, 0xBF}
+	// charsetRegexp matches <meta charset="...">, the charset parameter of
+	// <meta http-equiv="Content-Type" content="..."> and the encoding of an
+	// XML declaration.
+	charsetRegexp = regexp.MustCompile(`(?i)(?:<meta[^>]+charset|<\?xml[^>]+encoding)\s*=\s*["']?([\w:.-]+)`)
+)
+
+// DecodeText converts text of an upload to UTF-8 and tells which encoding it was
+// written in. The encoding is detected from a byte order mark, a charset
+// declaration or, if neither is present, the bytes used.
+func DecodeText(data []byte) ([]byte, string) {
+	name := detectEncoding(data)
+	if name == EncodingUTF8 {
+		return bytes.TrimPrefix(data, utf8BOM), name
+	}
+
+	text, err := encodings[name].NewDecoder().Bytes(data)
+	if err != nil {
+		// Single byte encodings can decode every byte.
+		return data, name
+	}
+	return text, name
+}
+
+// detectEncoding returns the name of the encoding data is most likely written in.
+func detectEncoding(data []byte) string {
+	if bytes.HasPrefix(data, utf8BOM) {
+		return EncodingUTF8
+	}
+
+	head := data
+	if len(head) > 2048 {
+		head = head[:2048]
+	}
+	declared := ""
+	if match := charsetRegexp.FindSubmatch(head); match != nil {
+		declared = encodingLabels[strings.ToLower(string(match[1]))]
+	}
+
+	// Hand edited files often keep the declaration of the original export
+	// while being saved as UTF-8, so valid multi-byte sequences win over the
+	// declaration. They hardly ever occur by chance in single byte encodings.
+	if utf8.Valid(data) && (declared == "" || declared == EncodingUTF8 || !isASCII(data)) {
+		return EncodingUTF8
+	}
+	if declared != "" && declared != EncodingUTF8 {
+		return declared
+	}
+
+	// Bytes 0x80 to 0x9F are control characters in ISO-8859-1 but printable
+	// characters like „ and “ in Windows-1252.
+	for _, b := range data {
+		if b >= 0x80 && b <= 0x9F {
+			return EncodingWindows1252
+		}
+	}
+	return EncodingISO8859_1
+}
+
+func isASCII(data []byte) bool {
+	for _, b := range data {
+		if b >= utf8.RuneSelf {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,61 @@
+package model
+
+import (
+	"bytes"
+	"testing"
+
+	"golang.org/x/text/encoding/charmap"
+)
+
+func TestDetectEncoding(t *testing.T) {
+	latin1, _ := charmap.ISO8859_1.NewEncoder().String("Müller")
+	windows, _ := charmap.Windows1252.NewEncoder().String("„Müller“")
+	tests := []struct {
+		data     string
+		expected string
+	}{
+		{"\xEF\xBB\xBFMüller", EncodingUTF8},
+		{`<meta charset="utf-8">Müller`, EncodingUTF8},
+		{`<meta http-equiv="Content-Type" content="text/html; charset=iso-8859-1">` + latin1, EncodingISO8859_1},
+		{`<meta http-equiv="Content-Type" content="text/html; charset=windows-1252">` + latin1, EncodingWindows1252},
+		{`<meta http-equiv="Content-Type" content="text/html; charset=iso-8859-1">Müller`, EncodingUTF8},
+		{`<meta charset="utf-8">` + latin1, EncodingISO8859_1},
+		{"Müller", EncodingUTF8},
+		{latin1, EncodingISO8859_1},
+		{windows, EncodingWindows1252},
+	}
+
+	for _, test := range tests {
+		if actual := detectEncoding([]byte(test.data)); actual != test.expected {
+			t.Errorf("Detected %s instead of %s for %q.", actual, test.expected, test.data)
+		}
+	}
+}
+
+func TestDecodeText(t *testing.T) {
+	windows, _ := charmap.Windows1252.NewEncoder().String("„Müller“")
+	text, encoding := DecodeText([]byte(windows))
+	if string(text) != "„Müller“" || encoding != EncodingWindows1252 {
+		t.Errorf("Decoded %q as %s.", text, encoding)
+	}
+
+	text, encoding = DecodeText([]byte("\xEF\xBB\xBFMüller"))
+	if string(text) != "Müller" || encoding != EncodingUTF8 {
+		t.Errorf("Decoded %q as %s.", text, encoding)
+	}
+}
+
+func TestToPlanEncoding(t *testing.T) {
+	latin1 := readTestPages(t, "subst.htm")[0]
+	utf8, _ := charmap.ISO8859_1.NewDecoder().Bytes(latin1)
+
+	for _, page := range [][]byte{latin1, utf8} {
+		plan, err := ToPlan(bytes.NewReader(page))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if short := plan.Parts[0].Substitutions[0].SubstTeacher.Short; short != "MÜL" {
+			t.Errorf("Short read as %q from %s file.", short, plan.Encoding)
+		}
+	}
+}
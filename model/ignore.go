@@ -0,0 +1,67 @@
+package model
+
+import "regexp"
+
+// IgnorePattern is a regular expression matching shorts which are no
+// teachers or subjects, e.g. "???" for a missing substitute. Matching shorts
+// are removed from plans as they are read.
+type IgnorePattern struct {
+	ID      int64
+	Pattern string
+}
+
+const ignore_pattern_schema = `CREATE TABLE ignore_patterns (pattern TEXT UNIQUE)`
+
+// defaultIgnorePatterns are the patterns the table is created with.
+var defaultIgnorePatterns = []string{`^\?\?\?$`, `^\+$`, `^---$`}
+
+// ReadAllIgnorePatterns fetches all ignore patterns.
+func ReadAllIgnorePatterns() []IgnorePattern {
+	var patterns []IgnorePattern
+	db.Select(&patterns, `SELECT rowid AS id, pattern FROM ignore_patterns ORDER BY rowid asc`)
+	return patterns
+}
+
+// Create inserts this pattern if it is a valid regular expression.
+func (p *IgnorePattern) Create() error {
+	if _, err := regexp.Compile(p.Pattern); err != nil {
+		return err
+	}
+	result, err := db.Exec(`INSERT INTO ignore_patterns(pattern) VALUES (?)`, p.Pattern)
+	if err != nil {
+		return err
+	}
+	p.ID, _ = result.LastInsertId()
+	return nil
+}
+
+// Exists tells whether there is a pattern with this pattern's id.
+func (p *IgnorePattern) Exists() bool {
+	var count int
+	db.Get(&count, "SELECT count(*) FROM ignore_patterns WHERE rowid = ?", p.ID)
+	return count > 0
+}
+
+// Delete removes this pattern.
+func (p *IgnorePattern) Delete() {
+	db.Exec(`DELETE FROM ignore_patterns WHERE rowid = ?`, p.ID)
+}
+
+// ignoredShort returns a function telling whether a short matches one of the
+// ignore patterns.
+func ignoredShort() func(string) bool {
+	var patterns []*regexp.Regexp
+	for _, p := range ReadAllIgnorePatterns() {
+		if re, err := regexp.Compile(p.Pattern); err == nil {
+			patterns = append(patterns, re)
+		}
+	}
+	return func(short string) bool {
+		for _, re := range patterns {
+			if short != "" && re.MatchString(short) {
+				return true
+			}
+		}
+		return false
+	}
+}
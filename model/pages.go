@@ -0,0 +1,168 @@
+package model
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Errors returned by MergePlans.
+var (
+	ErrDuplicatePage = errors.New("model: page uploaded twice with different content")
+	ErrMissingPage   = errors.New("model: page missing")
+)
+
+// UnpackPages splits an upload into the plan pages it contains. Zip archives are
+// unpacked and their .htm/.html files are returned ordered by name. Every other
+// upload is regarded as a single page.
+func UnpackPages(upload []byte) ([][]byte, error) {
+	if !bytes.HasPrefix(upload, []byte("PK\x03\x04")) {
+		return [][]byte{upload}, nil
+	}
+
+	archive, err := zip.NewReader(bytes.NewReader(upload), int64(len(upload)))
+	if err != nil {
+		return nil, err
+	}
+
+	files := make([]*zip.File, 0, len(archive.File))
+	for _, file := range archive.File {
+		name := strings.ToLower(file.Name)
+		if strings.HasSuffix(name, ".htm") || strings.HasSuffix(name, ".html") {
+			files = append(files, file)
+		}
+	}
+	sort.Slice(files, func(i, j int) bool { return files[i].Name < files[j].Name })
+
+	pages := make([][]byte, 0, len(files))
+	for _, file := range files {
+		rc, err := file.Open()
+		if err != nil {
+			return nil, err
+		}
+		page, err := ioutil.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			return nil, err
+		}
+		pages = append(pages, page)
+	}
+	if len(pages) == 0 {
+		return nil, errors.New("model: no plan pages in zip archive")
+	}
+	return pages, nil
+}
+
+// PackPages is the counterpart to UnpackPages. A single page is returned as is,
+// multiple pages are packed into a zip archive named like the Untis export.
+func PackPages(pages [][]byte) ([]byte, error) {
+	if len(pages) == 1 {
+		return pages[0], nil
+	}
+
+	var buf bytes.Buffer
+	archive := zip.NewWriter(&buf)
+	for i, page := range pages {
+		w, err := archive.Create(fmt.Sprintf("subst_%03d.htm", i+1))
+		if err != nil {
+			return nil, err
+		}
+		if _, err = w.Write(page); err != nil {
+			return nil, err
+		}
+	}
+	if err := archive.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// ToPlanPages reads every page with ToPlan and merges the results into one plan.
+func ToPlanPages(pages [][]byte) (*Plan, error) {
+	plans := make([]*Plan, 0, len(pages))
+	for i, page := range pages {
+		plan, err := ToPlan(bytes.NewReader(page))
+		if err != nil {
+			return &Plan{}, fmt.Errorf("page file %d: %v", i+1, err)
+		}
+		plans = append(plans, plan)
+	}
+	return MergePlans(plans)
+}
+
+// MergePlans merges the plans read from the pages of one export into a single
+// plan. Parts of the same day are joined in the order of their page numbers.
+// A page uploaded twice is used once if both copies are equal, otherwise
+// ErrDuplicatePage is returned. If a page of a day is missing, ErrMissingPage
+// is returned.
+func MergePlans(plans []*Plan) (*Plan, error) {
+	type day struct {
+		day   time.Time
+		pages int
+		parts map[int]Part
+	}
+	days := make(map[string]*day)
+	merged := &Plan{}
+
+	for _, plan := range plans {
+		if plan.Created.After(merged.Created) {
+			merged.Created = plan.Created
+		}
+		if merged.Encoding == "" {
+			merged.Encoding = plan.Encoding
+		} else if plan.Encoding != "" && !strings.Contains(merged.Encoding, plan.Encoding) {
+			merged.Encoding += ", " + plan.Encoding
+		}
+		for _, part := range plan.Parts {
+			page, pages := part.page, part.pages
+			if pages == 0 {
+				page, pages = 1, 1
+			}
+
+			key := part.Day.Format("2006-01-02")
+			d, ok := days[key]
+			if !ok {
+				d = &day{day: part.Day, parts: make(map[int]Part)}
+				days[key] = d
+			}
+			if pages > d.pages {
+				d.pages = pages
+			}
+
+			if other, ok := d.parts[page]; ok {
+				if !reflect.DeepEqual(other.Substitutions, part.Substitutions) {
+					return &Plan{}, fmt.Errorf("%w: %s page %d", ErrDuplicatePage, key, page)
+				}
+				continue
+			}
+			d.parts[page] = part
+		}
+	}
+
+	for key, d := range days {
+		part := Part{Day: d.day, Substitutions: []Substitution{}, page: 1, pages: 1}
+		for page := 1; page <= d.pages; page++ {
+			p, ok := d.parts[page]
+			if !ok {
+				return &Plan{}, fmt.Errorf("%w: %s page %d of %d", ErrMissingPage, key, page, d.pages)
+			}
+			for _, news := range p.News {
+				if !contains(part.News, news) {
+					part.News = append(part.News, news)
+				}
+			}
+			part.Substitutions = append(part.Substitutions, p.Substitutions...)
+		}
+		part.identify()
+		merged.Parts = append(merged.Parts, part)
+	}
+	sort.Slice(merged.Parts, func(i, j int) bool { return merged.Parts[i].Day.Before(merged.Parts[j].Day) })
+
+	return merged, nil
+}
@@ -0,0 +1,102 @@
+package model
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"reflect"
+	"testing"
+)
+
+func readTestPages(t *testing.T, names ...string) [][]byte {
+	pages := make([][]byte, 0, len(names))
+	for _, name := range names {
+		page, err := ioutil.ReadFile("testdata/" + name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		pages = append(pages, page)
+	}
+	return pages
+}
+
+func TestToPlanPages(t *testing.T) {
+	pages := readTestPages(t, "subst_002.htm", "subst_001.htm")
+	plan, err := ToPlanPages(pages)
+	if err != nil {
+		t.Fatalf("Merging pages failed: %v", err)
+	}
+
+	if len(plan.Parts) != 1 {
+		t.Fatalf("Expected one day, got %d.", len(plan.Parts))
+	}
+	substitutions := plan.Parts[0].Substitutions
+	if len(substitutions) != 3 {
+		t.Fatalf("Expected 3 substitutions, got %d.", len(substitutions))
+	}
+	if substitutions[0].Class != "5a" || substitutions[2].Class != "7c" {
+		t.Errorf("Pages were not merged in order: %+v", substitutions)
+	}
+}
+
+func TestToPlanPagesDuplicate(t *testing.T) {
+	pages := readTestPages(t, "subst_001.htm", "subst_001.htm", "subst_002.htm")
+	plan, err := ToPlanPages(pages)
+	if err != nil {
+		t.Fatalf("Equal duplicates should be ignored: %v", err)
+	}
+	if len(plan.Parts[0].Substitutions) != 3 {
+		t.Errorf("Duplicate page was merged: %+v", plan.Parts[0].Substitutions)
+	}
+
+	changed, _ := ToPlan(bytes.NewReader(readTestPages(t, "subst_001.htm")[0]))
+	changed.Parts[0].Substitutions[0].Class = "9z"
+	_, err = MergePlans([]*Plan{plan, changed})
+	if !errors.Is(err, ErrDuplicatePage) {
+		t.Errorf("Different duplicate wasn't detected: %v", err)
+	}
+}
+
+func TestToPlanPagesMissing(t *testing.T) {
+	_, err := ToPlanPages(readTestPages(t, "subst_002.htm"))
+	if !errors.Is(err, ErrMissingPage) {
+		t.Errorf("Missing page wasn't detected: %v", err)
+	}
+}
+
+func TestPackPages(t *testing.T) {
+	pages := readTestPages(t, "subst_001.htm", "subst_002.htm")
+
+	packed, err := PackPages(pages)
+	if err != nil {
+		t.Fatal(err)
+	}
+	unpacked, err := UnpackPages(packed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(unpacked) != 2 || string(unpacked[0]) != string(pages[0]) || string(unpacked[1]) != string(pages[1]) {
+		t.Error("Pages changed by packing and unpacking.")
+	}
+
+	single, _ := PackPages(pages[:1])
+	unpacked, _ = UnpackPages(single)
+	if len(unpacked) != 1 || string(unpacked[0]) != string(pages[0]) {
+		t.Error("Single page changed by packing and unpacking.")
+	}
+}
+
+func TestToPlanNews(t *testing.T) {
+	plan, err := ToPlanPages(readTestPages(t, "subst.htm"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{"Gesamtkonferenz ab 13:30 Uhr,", "die 7. und 8. Stunde entfallen für alle Klassen."}
+	if !reflect.DeepEqual(plan.Parts[0].News, expected) {
+		t.Errorf("Read news %q, expected %q.", plan.Parts[0].News, expected)
+	}
+	if len(plan.Parts[1].News) != 0 {
+		t.Errorf("Read news %q for day without news.", plan.Parts[1].News)
+	}
+}
@@ -1,7 +1,15 @@
 package model
 
 import (
+	"crypto/sha1"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
 	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
 	"time"
 )
 
@@ -9,16 +17,26 @@ import (
 type Plan struct {
 	Created time.Time
 	Parts   []Part
+
+	// Encoding is the name of the encoding the plan file was written in.
+	Encoding string `json:"-"`
 }
 
 // Part represents the list of substitutions for one day.
 type Part struct {
-	Day           time.Time
+	Day time.Time
+	// News are the daily news ("Nachrichten zum Tag"), one entry per line.
+	News          []string
 	Substitutions []Substitution
+
+	// page and pages locate this part in a multi-page export.
+	page, pages int
 }
 
 // Substitutions represents the substitution's information.
 type Substitution struct {
+	// ID identifies the substitution across uploads. See Part.identify.
+	ID           string
 	Period       string
 	Class        string
 	SubstTeacher Teacher
@@ -26,23 +44,192 @@ type Substitution struct {
 	InstdSubject Subject
 	Kind         string
 	Text         string
-	TaskProvider Teacher
+	// TaskProvider is the first of the TaskProviders.
+	TaskProvider  Teacher
+	TaskProviders []Teacher
+	// Rooms, MovedTo and MovedFrom are read from Text. MovedTo and MovedFrom
+	// tell where a lesson was moved to or from, e.g. "Di 3. Std.".
+	Rooms     []string
+	MovedTo   string
+	MovedFrom string
 }
 
-const plan_schema = `CREATE TABLE plans (upload DATETIME UNIQUE, json TEXT, file BLOB)`
+// identify assigns every substitution of this part its ID. The ID is derived
+// from the day, class, period and the teacher and subject that are substituted,
+// so the same lesson gets the same ID in every upload, even if the substitute
+// teacher, kind or text change. Substitutions that share all of these get a
+// running number appended.
+func (part *Part) identify() {
+	seen := make(map[string]int)
+	for i := range part.Substitutions {
+		s := &part.Substitutions[i]
+		key := strings.Join([]string{part.Day.Format("2006-01-02"), s.Class, s.Period,
+			s.InstdTeacher.Short, s.InstdSubject.Short}, "\x00")
+		sum := sha1.Sum([]byte(key))
+		id := hex.EncodeToString(sum[:8])
+
+		seen[id]++
+		if n := seen[id]; n > 1 {
+			id = fmt.Sprintf("%s-%d", id, n)
+		}
+		s.ID = id
+	}
+}
+
+// content returns the information of this substitution read from the plan file,
+// without the names that were looked up for the shorts.
+func (s *Substitution) content() string {
+	providers := make([]string, len(s.TaskProviders))
+	for i, t := range s.TaskProviders {
+		providers[i] = t.Short
+	}
+	return strings.Join([]string{s.ID, s.Period, s.Class, s.SubstTeacher.Short, s.InstdTeacher.Short,
+		s.InstdSubject.Short, s.Kind, s.Text, strings.Join(providers, ","), strings.Join(s.Rooms, ","),
+		s.MovedTo, s.MovedFrom}, "\x00")
+}
+
+// Hash returns a hash of the plan's days and substitutions. Plans exported
+// again without changes have the same hash, even if they were created later.
+func (plan *Plan) Hash() string {
+	hash := sha256.New()
+	for _, part := range plan.Parts {
+		fmt.Fprintf(hash, "%s\x00", part.Day.Format("2006-01-02"))
+		for _, news := range part.News {
+			fmt.Fprintf(hash, "%s\x00", news)
+		}
+		for i := range part.Substitutions {
+			fmt.Fprintf(hash, "%s\x00", part.Substitutions[i].content())
+		}
+	}
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+const plan_schema = `CREATE TABLE plans (id INTEGER PRIMARY KEY, upload DATETIME UNIQUE, json TEXT, file BLOB, encoding TEXT, hash TEXT, confirmed DATETIME, previous_json TEXT)`
+
+// Create saves this plan to the database as the newest plan and returns the
+// plan's id. If the newest plan has the same hash, nothing is saved but the
+// time the newest plan was confirmed, its id is returned and stored is false.
+func (plan *Plan) Create(file []byte) (id int64, stored bool, err error) {
+	hash := plan.Hash()
+	now := time.Now()
+
+	var last struct {
+		ID   int64
+		Hash sql.NullString
+	}
+	err = db.Get(&last, "SELECT id, hash FROM plans ORDER BY upload DESC LIMIT 1")
+	if err == nil && last.Hash.String == hash {
+		_, err = db.Exec(`UPDATE plans SET confirmed = ? WHERE id = ?`, now, last.ID)
+		return last.ID, false, err
+	}
 
-// Create saves this plan to the database as the newest plan.
-func (plan *Plan) Create(file []byte) {
 	json, _ := json.Marshal(*plan)
-	upload := time.Now()
+	stmt := `INSERT INTO plans (upload, json, file, encoding, hash, confirmed) VALUES (?, ?, ?, ?, ?, ?)`
+	result, err := db.Exec(stmt, now, string(json), file, plan.Encoding, hash, now)
+	if err != nil {
+		return 0, false, err
+	}
+	id, err = result.LastInsertId()
+	return id, err == nil, err
+}
+
+// plan_version_schema counts the changes of the plans in the database, so
+// changes made by another process, e.g. the reprocess command, are noticed.
+const plan_version_schema = `CREATE TABLE plan_version (version INTEGER NOT NULL);
+INSERT INTO plan_version (version) VALUES (0)`
 
-	stmt := `INSERT INTO plans (upload, json, file) VALUES (?, ?, ?)`
-	db.Exec(stmt, upload, string(json), file)
+// plan_version_triggers bump the plan version. They are created on every
+// start, as they are dropped with the plans table by migratePlanIDs.
+const plan_version_triggers = `CREATE TRIGGER IF NOT EXISTS plans_insert AFTER INSERT ON plans BEGIN UPDATE plan_version SET version = version + 1; END;
+CREATE TRIGGER IF NOT EXISTS plans_update AFTER UPDATE OF json ON plans BEGIN UPDATE plan_version SET version = version + 1; END;
+CREATE TRIGGER IF NOT EXISTS plans_delete AFTER DELETE ON plans BEGIN UPDATE plan_version SET version = version + 1; END`
+
+// migratePlanIDs gives the plans table an id column. Before, the rowid was
+// used as id, which VACUUM may renumber. The ids keep their values.
+func migratePlanIDs() {
+	var schema string
+	var columns []string
+	db.Get(&schema, `SELECT sql FROM sqlite_master WHERE type = 'table' AND name = 'plans'`)
+	db.Select(&columns, `SELECT name FROM pragma_table_info('plans')`)
+	schema = "CREATE TABLE plans_new (id INTEGER PRIMARY KEY, " + schema[strings.Index(schema, "(")+1:]
+	list := strings.Join(columns, ", ")
+
+	tx := db.MustBegin()
+	tx.MustExec(schema)
+	tx.MustExec(`INSERT INTO plans_new (id, ` + list + `) SELECT rowid, ` + list + ` FROM plans`)
+	tx.MustExec(`DROP TABLE plans`)
+	tx.MustExec(`ALTER TABLE plans_new RENAME TO plans`)
+	if err := tx.Commit(); err != nil {
+		panic(err)
+	}
+}
+
+// lastPlan caches the last plan resolved with the current teachers and
+// subjects. It is valid as long as neither the plans nor the directory
+// changed.
+var lastPlan struct {
+	sync.Mutex
+	planVersion      int
+	directoryVersion int
+	valid            bool
+	upload           PlanUpload
+	plan             *Plan
+	json             string
 }
 
-// LastPlanJSON returns the last plan in JSON format.
-func LastPlanJSON() string {
-	var json string
-	db.Get(&json, "SELECT json FROM plans ORDER BY upload DESC LIMIT 1")
-	return json
+// PlanVersion returns a number that changes whenever plans are stored,
+// reprocessed or removed, also by another process.
+func PlanVersion() int {
+	var version int
+	db.Get(&version, `SELECT version FROM plan_version`)
+	return version
+}
+
+// LastPlan returns the last plan resolved with the current teachers and subjects
+// or nil if there isn't any plan. The plan is shared and must not be changed.
+func LastPlan() *Plan {
+	_, plan, _ := readLastPlan()
+	return plan
+}
+
+// LastPlanJSON returns the last plan in JSON format with its upload. json is
+// empty if there isn't any plan.
+func LastPlanJSON() (upload PlanUpload, json string) {
+	upload, _, json = readLastPlan()
+	return upload, json
+}
+
+func readLastPlan() (PlanUpload, *Plan, string) {
+	planVersion, directoryVersion := PlanVersion(), DirectoryVersion()
+
+	lastPlan.Lock()
+	defer lastPlan.Unlock()
+	if lastPlan.valid && lastPlan.planVersion == planVersion && lastPlan.directoryVersion == directoryVersion {
+		return lastPlan.upload, lastPlan.plan, lastPlan.json
+	}
+
+	// The upload is read with its plan, so both belong together even if a
+	// plan is uploaded meanwhile.
+	var stored struct {
+		planUploadRow
+		JSON sql.NullString
+	}
+	err := db.Get(&stored, `SELECT `+planUploadColumns+`, json FROM plans ORDER BY upload DESC LIMIT 1`)
+	if err != nil || stored.JSON.String == "" {
+		return PlanUpload{}, nil, ""
+	}
+	plan := &Plan{}
+	if err := json.Unmarshal([]byte(stored.JSON.String), plan); err != nil {
+		log.Printf("error reading last plan: %v\n", err)
+		return PlanUpload{}, nil, ""
+	}
+	plan.Resolve()
+	resolved, _ := json.Marshal(*plan)
+
+	lastPlan.valid = true
+	lastPlan.planVersion, lastPlan.directoryVersion = planVersion, directoryVersion
+	lastPlan.upload = stored.planUpload()
+	lastPlan.plan = plan
+	lastPlan.json = string(resolved)
+	return lastPlan.upload, lastPlan.plan, lastPlan.json
 }
@@ -0,0 +1,100 @@
+package model
+
+import (
+	"testing"
+	"time"
+)
+
+func TestPartIdentify(t *testing.T) {
+	day := time.Date(2016, 10, 19, 0, 0, 0, 0, time.UTC)
+	part := Part{Day: day, Substitutions: []Substitution{
+		Substitution{Class: "5a", Period: "1", InstdTeacher: Teacher{Short: "Md"}, Kind: "Vertretung"},
+		Substitution{Class: "5a", Period: "2", InstdTeacher: Teacher{Short: "Md"}},
+		Substitution{Class: "5a", Period: "1", InstdTeacher: Teacher{Short: "Md"}, Kind: "Entfall"}}}
+	part.identify()
+
+	ids := []string{part.Substitutions[0].ID, part.Substitutions[1].ID, part.Substitutions[2].ID}
+	if ids[0] == "" || ids[0] == ids[1] || ids[0] == ids[2] || ids[2] != ids[0]+"-2" {
+		t.Errorf("Unexpected IDs: %v", ids)
+	}
+
+	// A changed substitute teacher or kind doesn't change the ID.
+	part.Substitutions[0].SubstTeacher = Teacher{Short: "Zl"}
+	part.Substitutions[0].Kind = "Statt-Vertretung"
+	part.identify()
+	if part.Substitutions[0].ID != ids[0] {
+		t.Error("ID changed with substitute teacher.")
+	}
+
+	// Another day gives another ID.
+	part.Day = day.AddDate(0, 0, 1)
+	part.identify()
+	if part.Substitutions[0].ID == ids[0] {
+		t.Error("ID didn't change with day.")
+	}
+}
+
+func TestCompare(t *testing.T) {
+	day := time.Date(2016, 10, 19, 0, 0, 0, 0, time.UTC)
+	old := &Plan{Parts: []Part{
+		Part{Day: day.AddDate(0, 0, -1), Substitutions: []Substitution{
+			Substitution{Class: "9a", Period: "1"}}},
+		Part{Day: day, Substitutions: []Substitution{
+			Substitution{Class: "5a", Period: "1", Kind: "Vertretung"},
+			Substitution{Class: "6b", Period: "2"},
+			Substitution{Class: "7c", Period: "3"}}}}}
+	new := &Plan{Parts: []Part{
+		Part{Day: day, Substitutions: []Substitution{
+			Substitution{Class: "5a", Period: "1", Kind: "Entfall"},
+			Substitution{Class: "7c", Period: "3"},
+			Substitution{Class: "8d", Period: "4"}}},
+		Part{Day: day.AddDate(0, 0, 1), Substitutions: []Substitution{
+			Substitution{Class: "10e", Period: "5"}}}}}
+	for _, plan := range []*Plan{old, new} {
+		for i := range plan.Parts {
+			plan.Parts[i].identify()
+		}
+	}
+
+	kinds := make(map[string]string)
+	for _, change := range Compare(old, new) {
+		kinds[change.Substitution().Class] = change.Kind()
+	}
+	expected := map[string]string{"5a": ChangeChanged, "6b": ChangeRemoved, "8d": ChangeAdded, "10e": ChangeAdded}
+	if len(kinds) != len(expected) {
+		t.Errorf("Unexpected changes: %v", kinds)
+	}
+	for class, kind := range expected {
+		if kinds[class] != kind {
+			t.Errorf("Substitution of %s should be %s, is %q.", class, kind, kinds[class])
+		}
+	}
+}
+
+func TestPlanCreate(t *testing.T) {
+	day := time.Date(2016, 10, 19, 0, 0, 0, 0, time.UTC)
+	plan := &Plan{Created: day, Parts: []Part{Part{Day: day, Substitutions: []Substitution{
+		Substitution{Class: "5a", Period: "1", Kind: "Vertretung"}}}}}
+	plan.Parts[0].identify()
+
+	id, stored, err := plan.Create([]byte("file"))
+	if err != nil || !stored || id == 0 {
+		t.Fatal("Plan wasn't stored.")
+	}
+
+	// The same plan exported again later isn't stored again.
+	again := *plan
+	again.Created = day.Add(time.Hour)
+	if again.Hash() != plan.Hash() {
+		t.Error("Hash depends on the time the plan was created.")
+	}
+	if againID, stored, _ := again.Create([]byte("file")); stored || againID != id {
+		t.Errorf("Unchanged plan was stored again (%d, %v).", againID, stored)
+	}
+
+	// A changed plan is stored.
+	plan.Parts[0].Substitutions[0].Kind = "Entfall"
+	if changedID, stored, _ := plan.Create([]byte("file")); !stored || changedID == id {
+		t.Error("Changed plan wasn't stored.")
+	}
+}
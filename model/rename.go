@@ -0,0 +1,115 @@
+package model
+
+import (
+	"errors"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// Errors returned by UpdateShort.
+var (
+	ErrEmptyShort = errors.New("model: short is empty")
+	ErrShortTaken = errors.New("model: short is taken")
+)
+
+// Rename records that a teacher's or subject's short was changed.
+type Rename struct {
+	// Kind is AliasTeacher or AliasSubject.
+	Kind string
+	Old  string
+	New  string
+	Time time.Time
+}
+
+const rename_schema = `CREATE TABLE renames (kind TEXT, old TEXT, new TEXT, time DATETIME)`
+
+// renameTables are the tables holding the shorts of each kind: the table of
+// the teachers or subjects and the table of the unknown shorts.
+var renameTables = map[string][2]string{
+	AliasTeacher: {"teachers", "unknown_teachers"},
+	AliasSubject: {"subjects", "unknown_subjects"},
+}
+
+// ReadRenames returns the renames which led to the short, the latest first.
+func ReadRenames(kind, short string) []Rename {
+	var renames []Rename
+	seen := map[string]bool{short: true}
+	for {
+		var rename Rename
+		err := db.Get(&rename, `SELECT kind, old, new, time FROM renames WHERE kind = ? AND new = ?
+			ORDER BY time desc LIMIT 1`, kind, short)
+		if err != nil || seen[rename.Old] {
+			return renames
+		}
+		renames = append(renames, rename)
+		seen[rename.Old] = true
+		short = rename.Old
+	}
+}
+
+// readRenamed returns the current shorts by former short.
+func readRenamed(kind string) map[string]string {
+	var renames []Rename
+	db.Select(&renames, `SELECT kind, old, new, time FROM renames WHERE kind = ? ORDER BY time asc`, kind)
+	renamed := make(map[string]string)
+	for _, r := range renames {
+		renamed[r.Old] = r.New
+		for old, short := range renamed {
+			if short == r.Old {
+				renamed[old] = r.New
+			}
+		}
+	}
+	return renamed
+}
+
+// renameShort changes the short of a teacher or subject in one transaction:
+// update changes the record itself, then the aliases are moved to the new
+// short, the old short becomes an alias, the rename is recorded and the new
+// short is no longer unknown. Shorts of other teachers or subjects and their
+// aliases can't be taken.
+func renameShort(kind, old, new string, update func(tx *sqlx.Tx) error) error {
+	tables := renameTables[kind]
+	if new == "" {
+		return ErrEmptyShort
+	}
+	if new != old {
+		var count int
+		db.Get(&count, `SELECT count(*) FROM `+tables[0]+` WHERE short = ?`, new)
+		if count == 0 {
+			db.Get(&count, `SELECT count(*) FROM aliases WHERE kind = ? AND alias = ? AND short != ?`, kind, new, old)
+		}
+		if count > 0 {
+			return ErrShortTaken
+		}
+	}
+
+	tx, err := db.Beginx()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err = update(tx); err != nil {
+		return err
+	}
+	if new != old {
+		statements := []struct {
+			query string
+			args  []interface{}
+		}{
+			{`UPDATE aliases SET short = ? WHERE kind = ? AND short = ?`, []interface{}{new, kind, old}},
+			{`DELETE FROM aliases WHERE kind = ? AND alias = ?`, []interface{}{kind, new}},
+			{`INSERT OR REPLACE INTO aliases(kind, alias, short) VALUES (?, ?, ?)`, []interface{}{kind, old, new}},
+			{`INSERT INTO renames(kind, old, new, time) VALUES (?, ?, ?, ?)`, []interface{}{kind, old, new, time.Now()}},
+			{`DELETE FROM ` + tables[1] + ` WHERE short = ?`, []interface{}{new}},
+		}
+		for _, s := range statements {
+			if _, err = tx.Exec(s.query, s.args...); err != nil {
+				return err
+			}
+		}
+	}
+	return tx.Commit()
+}
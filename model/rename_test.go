@@ -0,0 +1,60 @@
+package model
+
+import "testing"
+
+func TestUpdateShortRename(t *testing.T) {
+	teacher := Teacher{Short: "RnA", Name: "Alt", Sex: "w"}
+	teacher.Create()
+	other := Teacher{Short: "RnB", Name: "Andere", Sex: "m"}
+	other.Create()
+	otherAlias := Alias{Kind: AliasTeacher, Alias: "RnX", Short: "RnB"}
+	otherAlias.Create()
+	unknown := UnknownTeacher{Short: "RnC"}
+	unknown.Create()
+	defer other.Delete()
+
+	for _, short := range []string{"RnB", "RnX"} {
+		renamed := Teacher{Short: short, Name: "Alt", Sex: "w"}
+		if err := renamed.UpdateShort("RnA"); err != ErrShortTaken {
+			t.Errorf("Renaming onto %s gave %v, expected ErrShortTaken.", short, err)
+		}
+	}
+	if err := (&Teacher{Name: "Alt", Sex: "w"}).UpdateShort("RnA"); err != ErrEmptyShort {
+		t.Errorf("Renaming to an empty short gave %v.", err)
+	}
+
+	renamed := Teacher{Short: "RnC", Name: "Neu", Sex: "w"}
+	if err := renamed.UpdateShort("RnA"); err != nil {
+		t.Fatal(err)
+	}
+	renamed = Teacher{Short: "RnD", Name: "Neu", Sex: "w"}
+	if err := renamed.UpdateShort("RnC"); err != nil {
+		t.Fatal(err)
+	}
+	defer renamed.Delete()
+
+	if unknown.Read() {
+		t.Error("New short is still unknown.")
+	}
+	aliases := ReadAliases(AliasTeacher, "RnD")
+	if len(aliases) != 2 || aliases[0].Alias != "RnA" || aliases[1].Alias != "RnC" {
+		t.Errorf("Old shorts aren't aliases: %+v", aliases)
+	}
+	renames := ReadRenames(AliasTeacher, "RnD")
+	if len(renames) != 2 || renames[0].Old != "RnC" || renames[1].Old != "RnA" {
+		t.Errorf("Unexpected renames %+v.", renames)
+	}
+	if renamed := readRenamed(AliasTeacher); renamed["RnA"] != "RnD" {
+		t.Errorf("RnA was renamed to %s.", renamed["RnA"])
+	}
+
+	// Renaming back takes the alias as short again.
+	back := Teacher{Short: "RnA", Name: "Neu", Sex: "w"}
+	if err := back.UpdateShort("RnD"); err != nil {
+		t.Fatal(err)
+	}
+	defer back.Delete()
+	if aliases := ReadAliases(AliasTeacher, "RnA"); len(aliases) != 2 || aliases[0].Alias != "RnC" || aliases[1].Alias != "RnD" {
+		t.Errorf("Unexpected aliases %+v.", aliases)
+	}
+}
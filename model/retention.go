@@ -0,0 +1,135 @@
+package model
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// RetentionPolicy decides how long uploaded plans are kept. Uploads of the
+// current week are always kept completely, as is the newest upload. It also
+// decides how long teachers and subjects stay in the trash.
+type RetentionPolicy struct {
+	// Weeks is the number of weeks the last upload of each day is kept.
+	// Older uploads are removed.
+	Weeks int
+	// FileDays is the number of days the uploaded files are kept. After that
+	// only the plan read from the file remains.
+	FileDays int
+	// ArchiveDays is the number of days archived teachers and subjects are
+	// kept, see PurgeArchived. With 0 they are kept until deleted by hand.
+	ArchiveDays int
+}
+
+// DefaultRetention is the retention policy used unless configured otherwise.
+var DefaultRetention = RetentionPolicy{Weeks: 4, FileDays: 14, ArchiveDays: 365}
+
+// PruneReport lists the uploads affected by pruning.
+type PruneReport struct {
+	DryRun bool
+	// Deleted holds the upload times of the removed uploads.
+	Deleted []time.Time
+	// FilesDropped holds the upload times of the uploads whose file was removed.
+	FilesDropped []time.Time
+	// PurgedTeachers and PurgedSubjects hold the shorts of the teachers and
+	// subjects removed from the trash.
+	PurgedTeachers []string
+	PurgedSubjects []string
+}
+
+// String formats the report for logs and the command line.
+func (report PruneReport) String() string {
+	var b strings.Builder
+	verb := "removed"
+	if report.DryRun {
+		verb = "would remove"
+	}
+	fmt.Fprintf(&b, "%s %d uploads, %d files and %d archived teachers and subjects", verb,
+		len(report.Deleted), len(report.FilesDropped), len(report.PurgedTeachers)+len(report.PurgedSubjects))
+	for _, upload := range report.Deleted {
+		fmt.Fprintf(&b, "\nupload %s", upload.Format("2006-01-02 15:04:05"))
+	}
+	for _, upload := range report.FilesDropped {
+		fmt.Fprintf(&b, "\nfile of upload %s", upload.Format("2006-01-02 15:04:05"))
+	}
+	for _, short := range report.PurgedTeachers {
+		fmt.Fprintf(&b, "\narchived teacher %s", short)
+	}
+	for _, short := range report.PurgedSubjects {
+		fmt.Fprintf(&b, "\narchived subject %s", short)
+	}
+	return b.String()
+}
+
+// Prune removes the uploads, files and archived teachers and subjects the
+// policy doesn't keep any longer at the time now. With dryRun nothing is
+// removed, only the report is made. Removed uploads are audited as removed
+// by AuditSystem.
+func (policy RetentionPolicy) Prune(now time.Time, dryRun bool) (PruneReport, error) {
+	report := PruneReport{DryRun: dryRun}
+	if policy.ArchiveDays > 0 {
+		report.PurgedTeachers, report.PurgedSubjects = PurgeArchived(now.AddDate(0, 0, -policy.ArchiveDays), dryRun)
+	}
+
+	type storedUpload struct {
+		ID      int64
+		Upload  time.Time
+		HasFile bool
+	}
+	var uploads []storedUpload
+	err := db.Select(&uploads, `SELECT id, upload, file IS NOT NULL AS hasfile FROM plans ORDER BY upload DESC`)
+	if err != nil || len(uploads) == 0 {
+		return report, err
+	}
+
+	loc, _ := time.LoadLocation("Europe/Berlin")
+	now = now.In(loc)
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, loc)
+	weekStart := today.AddDate(0, 0, -(int(today.Weekday())+6)%7)
+	deleteBefore := today.AddDate(0, 0, -7*policy.Weeks)
+	dropFileBefore := today.AddDate(0, 0, -policy.FileDays)
+
+	tx, err := db.Beginx()
+	if err != nil {
+		return report, err
+	}
+	defer tx.Rollback()
+
+	// Uploads are ordered newest first, so the first upload seen of a day
+	// is the last upload of that day.
+	seenDays := make(map[string]bool)
+	var deleted []storedUpload
+	for i, upload := range uploads {
+		day := upload.Upload.In(loc).Format("2006-01-02")
+		lastOfDay := !seenDays[day]
+		seenDays[day] = true
+
+		if i == 0 || !upload.Upload.Before(weekStart) {
+			continue
+		}
+		if upload.Upload.Before(deleteBefore) || !lastOfDay {
+			report.Deleted = append(report.Deleted, upload.Upload)
+			deleted = append(deleted, upload)
+			if _, err = tx.Exec(`DELETE FROM plans WHERE id = ?`, upload.ID); err != nil {
+				return report, err
+			}
+		} else if upload.HasFile && upload.Upload.Before(dropFileBefore) {
+			report.FilesDropped = append(report.FilesDropped, upload.Upload)
+			if _, err = tx.Exec(`UPDATE plans SET file = NULL WHERE id = ?`, upload.ID); err != nil {
+				return report, err
+			}
+		}
+	}
+
+	if dryRun {
+		return report, nil
+	}
+	if err = tx.Commit(); err != nil {
+		return report, err
+	}
+	for _, upload := range deleted {
+		Audit(AuditSystem, AuditDelete, AuditPlan, strconv.FormatInt(upload.ID, 10), upload, nil)
+	}
+	return report, nil
+}
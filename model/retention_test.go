@@ -0,0 +1,86 @@
+package model
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRetentionPolicyPrune(t *testing.T) {
+	loc, _ := time.LoadLocation("Europe/Berlin")
+	at := func(day, hour int) time.Time { return time.Date(2016, 10, day, hour, 0, 0, 0, loc) }
+	uploads := map[string]time.Time{
+		"old":           at(1, 8),
+		"overwritten":   at(25, 7),
+		"lastOfDay":     at(25, 9),
+		"recent":        at(27, 9),
+		"thisWeekFirst": at(31, 7),
+		"thisWeekLast":  at(31, 8),
+	}
+	for _, upload := range uploads {
+		db.MustExec(`INSERT INTO plans (upload, json, file) VALUES (?, '{}', ?)`, upload, []byte("file"))
+	}
+	defer db.MustExec(`DELETE FROM plans WHERE upload < ?`, at(31, 23))
+
+	// Wednesday of the following week.
+	now := time.Date(2016, 11, 2, 12, 0, 0, 0, loc)
+	policy := RetentionPolicy{Weeks: 4, FileDays: 7}
+
+	exists := func(upload time.Time) (exists, hasFile bool) {
+		var files []bool
+		db.Select(&files, `SELECT file IS NOT NULL FROM plans WHERE upload = ?`, upload)
+		return len(files) > 0, len(files) > 0 && files[0]
+	}
+	check := func(report PruneReport) {
+		if len(report.Deleted) != 2 || len(report.FilesDropped) != 1 {
+			t.Errorf("Unexpected report: %v", report)
+		}
+		for _, deleted := range report.Deleted {
+			if !deleted.Equal(uploads["old"]) && !deleted.Equal(uploads["overwritten"]) {
+				t.Errorf("Removed %v.", deleted)
+			}
+		}
+		if len(report.FilesDropped) > 0 && !report.FilesDropped[0].Equal(uploads["lastOfDay"]) {
+			t.Errorf("Dropped file of %v.", report.FilesDropped[0])
+		}
+	}
+
+	report, err := policy.Prune(now, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	check(report)
+	if ok, _ := exists(uploads["old"]); !ok {
+		t.Error("Dry run removed an upload.")
+	}
+
+	var deletedIDs []string
+	db.Select(&deletedIDs, `SELECT id FROM plans WHERE upload IN (?, ?)`, uploads["old"], uploads["overwritten"])
+	pruned := time.Now()
+	report, err = policy.Prune(now, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	check(report)
+	for _, id := range deletedIDs {
+		if entries := ReadAudit(AuditFilter{Actor: AuditSystem, Action: AuditDelete, Entity: AuditPlan, Key: id, From: pruned}); len(entries) == 0 {
+			t.Errorf("Removal of upload %s wasn't audited.", id)
+		}
+	}
+	for name, upload := range uploads {
+		ok, hasFile := exists(upload)
+		switch name {
+		case "old", "overwritten":
+			if ok {
+				t.Errorf("Upload %s wasn't removed.", name)
+			}
+		case "lastOfDay":
+			if !ok || hasFile {
+				t.Errorf("Upload %s should be kept without file.", name)
+			}
+		default:
+			if !ok || !hasFile {
+				t.Errorf("Upload %s should be kept completely.", name)
+			}
+		}
+	}
+}
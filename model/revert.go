@@ -0,0 +1,261 @@
+package model
+
+import (
+	"encoding/json"
+	"errors"
+)
+
+// Errors returned by AuditEntry.Revert.
+var (
+	ErrNotRevertible  = errors.New("model: change can't be reverted")
+	ErrRevertConflict = errors.New("model: changed again since")
+)
+
+// revertedActions are the actions undoing the audited actions.
+var revertedActions = map[string]string{
+	AuditCreate:  AuditDelete,
+	AuditDelete:  AuditCreate,
+	AuditUpdate:  AuditUpdate,
+	AuditArchive: AuditRestore,
+	AuditRestore: AuditArchive,
+}
+
+// Revertible tells whether Revert can undo the change. Plan uploads, users
+// and deleted API tokens can't be brought back.
+func (e AuditEntry) Revertible() bool {
+	if _, ok := revertedActions[e.Action]; !ok {
+		return false
+	}
+	switch e.Entity {
+	case AuditTeacher, AuditSubject, AuditAlias, AuditDisplay, AuditIgnorePattern, AuditUnknownTeacher, AuditUnknownSubject:
+		return true
+	case AuditToken:
+		return e.Action == AuditCreate
+	}
+	return false
+}
+
+// Revert undoes the change, bringing back the entity as it was before. The
+// undoing is audited for the actor with the reverted action, e.g. AuditRestore
+// for AuditArchive, and the ID of its entry is returned. ErrRevertConflict is
+// returned if the entity was changed again since.
+func (e AuditEntry) Revert(actor string) (int64, error) {
+	if !e.Revertible() {
+		return 0, ErrNotRevertible
+	}
+	switch e.Entity {
+	case AuditTeacher:
+		// Deletes are audited with the aliases, see TeacherWithAliases.
+		var before TeacherWithAliases
+		var after Teacher
+		if err := e.decode(&before, &after); err != nil {
+			return 0, err
+		}
+		current := Teacher{Short: after.Short}
+		if e.After != "" {
+			if !current.Exists() {
+				return 0, ErrRevertConflict
+			}
+			if current.Read(); !sameTeacher(current, after) {
+				return 0, ErrRevertConflict
+			}
+		}
+		switch {
+		case e.Before == "":
+			deleted := current.WithAliases()
+			current.Delete()
+			return e.audit(actor, deleted, nil), nil
+		case e.After == "":
+			if before.Exists() {
+				return 0, ErrRevertConflict
+			}
+			before.Create()
+			for _, alias := range before.Aliases {
+				alias.Create()
+			}
+		case before.Short != after.Short:
+			if err := before.UpdateShort(after.Short); err != nil {
+				return 0, err
+			}
+		default:
+			before.Update()
+		}
+		before.SetInactive(before.Inactive)
+		archive("teachers", before.Short, before.ArchivedFrom, before.ArchivedUntil)
+		if e.After == "" {
+			return e.audit(actor, nil, before), nil
+		}
+		return e.audit(actor, current, before.Teacher), nil
+
+	case AuditSubject:
+		// Deletes are audited with the aliases, see SubjectWithAliases.
+		var before SubjectWithAliases
+		var after Subject
+		if err := e.decode(&before, &after); err != nil {
+			return 0, err
+		}
+		current := Subject{Short: after.Short}
+		if e.After != "" {
+			if !current.Exists() {
+				return 0, ErrRevertConflict
+			}
+			if current.Read(); !sameSubject(current, after) {
+				return 0, ErrRevertConflict
+			}
+		}
+		switch {
+		case e.Before == "":
+			deleted := current.WithAliases()
+			current.Delete()
+			return e.audit(actor, deleted, nil), nil
+		case e.After == "":
+			if before.Exists() {
+				return 0, ErrRevertConflict
+			}
+			before.Create()
+			for _, alias := range before.Aliases {
+				alias.Create()
+			}
+		case before.Short != after.Short:
+			if err := before.UpdateShort(after.Short); err != nil {
+				return 0, err
+			}
+		default:
+			before.Update()
+		}
+		archive("subjects", before.Short, before.ArchivedFrom, before.ArchivedUntil)
+		if e.After == "" {
+			return e.audit(actor, nil, before), nil
+		}
+		return e.audit(actor, current, before.Subject), nil
+
+	case AuditAlias:
+		var before, after Alias
+		if err := e.decode(&before, &after); err != nil {
+			return 0, err
+		}
+		if e.After != "" {
+			if !after.Exists() {
+				return 0, ErrRevertConflict
+			}
+			after.Delete()
+			return e.audit(actor, after, nil), nil
+		}
+		if before.Exists() {
+			return 0, ErrRevertConflict
+		}
+		before.Create()
+		return e.audit(actor, nil, before), nil
+
+	case AuditDisplay:
+		var before, after DisplayProfile
+		if err := e.decode(&before, &after); err != nil {
+			return 0, err
+		}
+		current := DisplayProfile{Name: e.Key}
+		if e.After != "" {
+			if current.Read(); !current.Exists() || current != after {
+				return 0, ErrRevertConflict
+			}
+		}
+		switch {
+		case e.Before == "":
+			current.Delete()
+			return e.audit(actor, current, nil), nil
+		case e.After == "":
+			if before.Exists() {
+				return 0, ErrRevertConflict
+			}
+			before.Create()
+			return e.audit(actor, nil, before), nil
+		}
+		before.Update()
+		return e.audit(actor, current, before), nil
+
+	case AuditIgnorePattern:
+		var before, after IgnorePattern
+		if err := e.decode(&before, &after); err != nil {
+			return 0, err
+		}
+		if e.After != "" {
+			if !after.Exists() {
+				return 0, ErrRevertConflict
+			}
+			after.Delete()
+			return e.audit(actor, after, nil), nil
+		}
+		if err := before.Create(); err != nil {
+			return 0, err
+		}
+		return e.audit(actor, nil, before), nil
+
+	case AuditUnknownTeacher, AuditUnknownSubject:
+		var before, after unknown
+		if err := e.decode(&before, &after); err != nil {
+			return 0, err
+		}
+		table := "unknown_teachers"
+		if e.Entity == AuditUnknownSubject {
+			table = "unknown_subjects"
+		}
+		current, ok := readUnknown(table, e.Key)
+		if ok != (e.After != "") || ok && current.Ignored != after.Ignored {
+			return 0, ErrRevertConflict
+		}
+		if !ok {
+			restoreUnknown(table, before)
+			return e.audit(actor, nil, before), nil
+		}
+		db.Exec(`UPDATE `+table+` SET ignored = ? WHERE short = ?`, before.Ignored, e.Key)
+		restored := current
+		restored.Ignored = before.Ignored
+		return e.audit(actor, current, restored), nil
+
+	case AuditToken:
+		var after APIToken
+		if err := e.decode(nil, &after); err != nil {
+			return 0, err
+		}
+		if !after.Exists() {
+			return 0, ErrRevertConflict
+		}
+		after.Delete()
+		return e.audit(actor, after, nil), nil
+	}
+	return 0, ErrNotRevertible
+}
+
+// decode reads the values before and after the change. Empty values are
+// left alone.
+func (e AuditEntry) decode(before, after interface{}) error {
+	if e.Before != "" && before != nil {
+		if err := json.Unmarshal([]byte(e.Before), before); err != nil {
+			return err
+		}
+	}
+	if e.After != "" && after != nil {
+		if err := json.Unmarshal([]byte(e.After), after); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// audit records the undoing of this change for the actor.
+func (e AuditEntry) audit(actor string, before, after interface{}) int64 {
+	return Audit(actor, revertedActions[e.Action], e.Entity, e.Key, before, after)
+}
+
+// sameTeacher tells whether the teachers are equal, comparing the times by
+// the instant they stand for.
+func sameTeacher(a, b Teacher) bool {
+	return a.Short == b.Short && a.Name == b.Name && a.Sex == b.Sex && a.Inactive == b.Inactive &&
+		a.ArchivedFrom.Equal(b.ArchivedFrom) && a.ArchivedUntil.Equal(b.ArchivedUntil)
+}
+
+// sameSubject tells whether the subjects are equal, comparing the times by
+// the instant they stand for.
+func sameSubject(a, b Subject) bool {
+	return a.Short == b.Short && a.Name == b.Name && a.SplitClass == b.SplitClass &&
+		a.ArchivedFrom.Equal(b.ArchivedFrom) && a.ArchivedUntil.Equal(b.ArchivedUntil)
+}
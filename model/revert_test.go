@@ -0,0 +1,89 @@
+package model
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRevert(t *testing.T) {
+	teacher := Teacher{Short: "RvA", Name: "Alt", Sex: "m"}
+	teacher.Create()
+	defer teacher.Delete()
+
+	// Archiving is reverted by restoring.
+	before := teacher
+	teacher.Archive(time.Now().Add(-time.Hour), time.Time{})
+	entry := AuditEntry{ID: Audit("reverttest", AuditArchive, AuditTeacher, teacher.Short, before, teacher)}
+	if !entry.Read() || !entry.Revertible() {
+		t.Fatalf("Entry wasn't read or isn't revertible: %+v", entry)
+	}
+	id, err := entry.Revert("reverttest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if teacher.Read(); !teacher.ArchivedFrom.IsZero() {
+		t.Error("Teacher wasn't restored.")
+	}
+	restore := AuditEntry{ID: id}
+	if !restore.Read() || restore.Action != AuditRestore || restore.Actor != "reverttest" {
+		t.Errorf("Revert wasn't audited: %+v", restore)
+	}
+	if _, err := entry.Revert("reverttest"); err != ErrRevertConflict {
+		t.Errorf("Reverting twice gave %v, expected ErrRevertConflict.", err)
+	}
+
+	// Updates changed again since aren't reverted.
+	before = teacher
+	teacher.Name = "Neu"
+	teacher.Update()
+	entry = AuditEntry{ID: Audit("reverttest", AuditUpdate, AuditTeacher, teacher.Short, before, teacher)}
+	entry.Read()
+	teacher.Name = "Neuer"
+	teacher.Update()
+	if _, err := entry.Revert("reverttest"); err != ErrRevertConflict {
+		t.Errorf("Reverting a changed teacher gave %v, expected ErrRevertConflict.", err)
+	}
+	teacher.Name = "Neu"
+	teacher.Update()
+	if _, err := entry.Revert("reverttest"); err != nil {
+		t.Fatal(err)
+	}
+	if teacher.Read(); teacher.Name != "Alt" {
+		t.Errorf("Update wasn't reverted: %+v", teacher)
+	}
+
+	// Purged subjects are brought back.
+	subject := Subject{Short: "RvS", Name: "Latein"}
+	subject.Create()
+	defer subject.Delete()
+	alias := Alias{Kind: AliasSubject, Alias: "RvL", Short: subject.Short}
+	alias.Create()
+	defer alias.Delete()
+	subject.Archive(time.Now().Add(-time.Hour), time.Time{})
+	subject.Read()
+	PurgeArchived(time.Now(), false)
+	if subject.Exists() || alias.Exists() {
+		t.Fatal("Subject or alias wasn't purged.")
+	}
+	purges := ReadAudit(AuditFilter{Actor: AuditSystem, Action: AuditDelete, Entity: AuditSubject, Key: subject.Short, Limit: 1})
+	if len(purges) != 1 {
+		t.Fatal("Purge wasn't audited.")
+	}
+	if _, err := purges[0].Revert("reverttest"); err != nil {
+		t.Fatal(err)
+	}
+	restored := Subject{Short: "RvS"}
+	if restored.Read(); restored.Name != "Latein" || restored.ArchivedFrom.IsZero() {
+		t.Errorf("Subject wasn't brought back to the trash: %+v", restored)
+	}
+	if aliases := ReadAliases(AliasSubject, subject.Short); len(aliases) != 1 || aliases[0] != alias {
+		t.Errorf("Aliases weren't brought back: %+v", aliases)
+	}
+
+	// Plan uploads can't be reverted.
+	entry = AuditEntry{ID: Audit("reverttest", AuditUpload, AuditPlan, "1", nil, nil)}
+	entry.Read()
+	if _, err := entry.Revert("reverttest"); err != ErrNotRevertible {
+		t.Errorf("Reverting an upload gave %v, expected ErrNotRevertible.", err)
+	}
+}
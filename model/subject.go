@@ -1,25 +1,87 @@
 package model
 
+import (
+	"database/sql"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
 // Subject represents a subject associating abbreviations (short)
 // with other subject information.
 type Subject struct {
 	Short      string
 	Name       string
 	SplitClass bool
+	// ArchivedFrom and ArchivedUntil limit the time the subject is in the
+	// trash, see Archive. Both are zero for subjects which aren't archived.
+	ArchivedFrom  time.Time
+	ArchivedUntil time.Time
+}
+
+// ArchivedAt tells whether the subject is in the trash at the given time.
+func (s Subject) ArchivedAt(at time.Time) bool {
+	return archivedAt(s.ArchivedFrom, s.ArchivedUntil, at)
 }
 
 const subject_schema = `CREATE TABLE subjects (short text, name text, splitclass boolean)`
 
+const subjectColumns = `short, name, splitclass, archived_from, archived_until`
+
+type subjectRow struct {
+	Short         string
+	Name          string
+	SplitClass    bool         `db:"splitclass"`
+	ArchivedFrom  sql.NullTime `db:"archived_from"`
+	ArchivedUntil sql.NullTime `db:"archived_until"`
+}
+
+func (row subjectRow) subject() Subject {
+	return Subject{Short: row.Short, Name: row.Name, SplitClass: row.SplitClass,
+		ArchivedFrom: row.ArchivedFrom.Time, ArchivedUntil: row.ArchivedUntil.Time}
+}
+
+// readSubjects returns all subjects including the archived ones.
+func readSubjects() []Subject {
+	var rows []subjectRow
+	db.Select(&rows, `SELECT `+subjectColumns+` FROM subjects ORDER BY name asc`)
+
+	subjects := make([]Subject, len(rows))
+	for i, row := range rows {
+		subjects[i] = row.subject()
+	}
+	return subjects
+}
+
 // ReadAllSubjects fetches all subject records from the database and
-// returns a slice with all subjects found.
+// returns a slice with all subjects found. Subjects in the trash are left
+// out, see ReadArchivedSubjects.
 func ReadAllSubjects() []Subject {
 	var subjects []Subject
-	db.Select(&subjects, `SELECT short, name, splitclass FROM subjects ORDER BY name asc`)
+	now := time.Now()
+	for _, s := range readSubjects() {
+		if !s.ArchivedAt(now) {
+			subjects = append(subjects, s)
+		}
+	}
+	return subjects
+}
 
+// ReadArchivedSubjects returns the subjects in the trash and the subjects
+// which are going to be archived.
+func ReadArchivedSubjects() []Subject {
+	var subjects []Subject
+	now := time.Now()
+	for _, s := range readSubjects() {
+		if !s.ArchivedFrom.IsZero() && (s.ArchivedUntil.IsZero() || s.ArchivedUntil.After(now)) {
+			subjects = append(subjects, s)
+		}
+	}
 	return subjects
 }
 
 // Exists tells whether there is a subject record with this subject's short.
+// Archived subjects exist as well.
 func (s *Subject) Exists() bool {
 	var count int
 	db.Get(&count, "SELECT count(*) FROM subjects WHERE short = ?", s.Short)
@@ -42,7 +104,10 @@ func (s *Subject) Create() {
 // Read completes this subject with the subject information associated
 // with this subject's short.
 func (s *Subject) Read() {
-	db.Get(s, "SELECT short, name, splitclass FROM subjects WHERE short = ?", s.Short)
+	var row subjectRow
+	if err := db.Get(&row, `SELECT `+subjectColumns+` FROM subjects WHERE short = ?`, s.Short); err == nil {
+		*s = row.subject()
+	}
 }
 
 // Update updates the subject record with the same short as this subject's short
@@ -53,14 +118,36 @@ func (s *Subject) Update() {
 }
 
 // UpdateShort updates the subject identified by the given short with the
-// data included in the given subject receiver.
-func (s *Subject) UpdateShort(short string) {
-	stmt := `UPDATE subjects SET short = ?, name = ?, splitclass = ? WHERE short = ?`
-	db.Exec(stmt, s.Short, s.Name, s.SplitClass, short)
+// data included in the given subject receiver. If the short changes, the old
+// short is kept as alias and the rename is recorded, see ReadRenames.
+// ErrShortTaken is returned if the new short is another subject's short or
+// alias.
+func (s *Subject) UpdateShort(short string) error {
+	return renameShort(AliasSubject, short, s.Short, func(tx *sqlx.Tx) error {
+		stmt := `UPDATE subjects SET short = ?, name = ?, splitclass = ? WHERE short = ?`
+		_, err := tx.Exec(stmt, s.Short, s.Name, s.SplitClass, short)
+		return err
+	})
+}
+
+// Archive moves this subject to the trash from the given time until the
+// given time. With zero until the subject stays in the trash until restored
+// or purged, see PurgeArchived. Archived subjects are still resolved in plans.
+func (s *Subject) Archive(from, until time.Time) {
+	s.ArchivedFrom, s.ArchivedUntil = from, until
+	archive("subjects", s.Short, from, until)
+}
+
+// Restore takes this subject out of the trash.
+func (s *Subject) Restore() {
+	s.ArchivedFrom, s.ArchivedUntil = time.Time{}, time.Time{}
+	archive("subjects", s.Short, time.Time{}, time.Time{})
 }
 
-// Delete removes this subject from the database.
+// Delete removes this subject and the subject's aliases from the database
+// for good. To keep the subject in the trash, use Archive.
 func (s *Subject) Delete() {
 	stmt := `DELETE FROM subjects WHERE short = ?`
 	db.Exec(stmt, s.Short)
+	db.Exec(`DELETE FROM aliases WHERE kind = ? AND short = ?`, AliasSubject, s.Short)
 }
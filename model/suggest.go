@@ -0,0 +1,118 @@
+package model
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// maxSuggestions is the number of suggestions made for an unknown short.
+const maxSuggestions = 5
+
+// Suggestion is a teacher or subject an unknown short may stand for.
+type Suggestion struct {
+	Short string
+	Name  string
+	// Reason tells why the teacher or subject is suggested.
+	Reason   string
+	distance int
+}
+
+// SuggestTeachers returns the teachers the short may stand for, the most
+// likely first.
+func SuggestTeachers(short string) []Suggestion {
+	names := make(map[string]string)
+	for _, t := range ReadAllTeachers() {
+		names[t.Short] = t.FullName()
+	}
+	return suggest(short, names, readRenamed(AliasTeacher))
+}
+
+// SuggestSubjects returns the subjects the short may stand for, the most
+// likely first.
+func SuggestSubjects(short string) []Suggestion {
+	names := make(map[string]string)
+	for _, s := range ReadAllSubjects() {
+		names[s.Short] = s.Name
+	}
+	return suggest(short, names, readRenamed(AliasSubject))
+}
+
+// suggest compares the short with the shorts of names ignoring case and the
+// spelling of umlauts, e.g. "MUE" and "Mü" are spelt alike. Other shorts are
+// suggested if they differ in one character, longer shorts in two. Shorts
+// renamed from a short spelt alike are suggested, too.
+func suggest(short string, names map[string]string, renamed map[string]string) []Suggestion {
+	folded := foldShort(short)
+	var suggestions []Suggestion
+	suggested := make(map[string]bool)
+	for old, candidate := range renamed {
+		if name, ok := names[candidate]; ok && candidate != short && foldShort(old) == folded {
+			suggestions = append(suggestions, Suggestion{Short: candidate, Name: name, Reason: "früher " + old})
+			suggested[candidate] = true
+		}
+	}
+	for candidate, name := range names {
+		if suggested[candidate] {
+			continue
+		}
+		if candidate == short {
+			continue
+		}
+		d := levenshtein(folded, foldShort(candidate))
+		switch {
+		case d == 0:
+			suggestions = append(suggestions, Suggestion{Short: candidate, Name: name, Reason: "andere Schreibweise"})
+		case d == 1 || d == 2 && len([]rune(folded)) >= 4:
+			suggestions = append(suggestions, Suggestion{Short: candidate, Name: name,
+				Reason: fmt.Sprintf("ähnlich (%d Zeichen anders)", d), distance: d})
+		}
+	}
+
+	sort.Slice(suggestions, func(i, j int) bool {
+		if suggestions[i].distance != suggestions[j].distance {
+			return suggestions[i].distance < suggestions[j].distance
+		}
+		return suggestions[i].Short < suggestions[j].Short
+	})
+	if len(suggestions) > maxSuggestions {
+		suggestions = suggestions[:maxSuggestions]
+	}
+	return suggestions
+}
+
+var umlautReplacer = strings.NewReplacer("ä", "ae", "ö", "oe", "ü", "ue", "ß", "ss")
+
+// foldShort returns the short in lower case with umlauts spelt out.
+func foldShort(short string) string {
+	return umlautReplacer.Replace(strings.ToLower(short))
+}
+
+// levenshtein returns the number of characters to insert, delete or
+// substitute to turn a into b.
+func levenshtein(a, b string) int {
+	s, t := []rune(a), []rune(b)
+	previous := make([]int, len(t)+1)
+	current := make([]int, len(t)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+	for i := 1; i <= len(s); i++ {
+		current[0] = i
+		for j := 1; j <= len(t); j++ {
+			cost := 1
+			if s[i-1] == t[j-1] {
+				cost = 0
+			}
+			current[j] = previous[j-1] + cost
+			if previous[j]+1 < current[j] {
+				current[j] = previous[j] + 1
+			}
+			if current[j-1]+1 < current[j] {
+				current[j] = current[j-1] + 1
+			}
+		}
+		previous, current = current, previous
+	}
+	return previous[len(t)]
+}
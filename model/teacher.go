@@ -1,25 +1,107 @@
 package model
 
+import (
+	"database/sql"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
 // Teacher represents a teacher associating abbreviations (short)
 // with name and compellation information.
 type Teacher struct {
 	Short string
 	Name  string
 	Sex   string
+	// Inactive teachers are no longer found in the Untis export. They are
+	// kept to resolve older plans.
+	Inactive bool
+	// ArchivedFrom and ArchivedUntil limit the time the teacher is in the
+	// trash, see Archive. Both are zero for teachers which aren't archived.
+	ArchivedFrom  time.Time
+	ArchivedUntil time.Time
+}
+
+// ArchivedAt tells whether the teacher is in the trash at the given time.
+func (t Teacher) ArchivedAt(at time.Time) bool {
+	return archivedAt(t.ArchivedFrom, t.ArchivedUntil, at)
+}
+
+// FullName returns the compellation and name of the teacher, e.g.
+// "Herr Müller" or "Frau Schmidt". Teachers without name are called by
+// their short.
+func (t Teacher) FullName() string {
+	switch {
+	case t.Name == "":
+		return t.Short
+	case t.Sex == "m":
+		return "Herr " + t.Name
+	case t.Sex == "w":
+		return "Frau " + t.Name
+	default:
+		return t.Name
+	}
 }
 
 const teacher_schema = `CREATE TABLE teachers (short TEXT UNIQUE, name TEXT, sex TEXT)`
 
+const teacherColumns = `short, name, sex, inactive, archived_from, archived_until`
+
+type teacherRow struct {
+	Short         string
+	Name          string
+	Sex           string
+	Inactive      bool
+	ArchivedFrom  sql.NullTime `db:"archived_from"`
+	ArchivedUntil sql.NullTime `db:"archived_until"`
+}
+
+func (row teacherRow) teacher() Teacher {
+	return Teacher{Short: row.Short, Name: row.Name, Sex: row.Sex, Inactive: row.Inactive,
+		ArchivedFrom: row.ArchivedFrom.Time, ArchivedUntil: row.ArchivedUntil.Time}
+}
+
+// readTeachers returns all teachers including the archived ones.
+func readTeachers() []Teacher {
+	var rows []teacherRow
+	db.Select(&rows, `SELECT `+teacherColumns+` FROM teachers ORDER BY name asc`)
+
+	teachers := make([]Teacher, len(rows))
+	for i, row := range rows {
+		teachers[i] = row.teacher()
+	}
+	return teachers
+}
+
 // ReadAllTeachers fetches all teacher records from the database and
-// returns a slice with all teachers found.
+// returns a slice with all teachers found. Teachers in the trash are left
+// out, see ReadArchivedTeachers.
 func ReadAllTeachers() []Teacher {
 	var teachers []Teacher
-	db.Select(&teachers, `SELECT short, name, sex FROM teachers ORDER BY name asc`)
+	now := time.Now()
+	for _, t := range readTeachers() {
+		if !t.ArchivedAt(now) {
+			teachers = append(teachers, t)
+		}
+	}
+	return teachers
+}
 
+// ReadArchivedTeachers returns the teachers in the trash and the teachers
+// which are going to be archived.
+func ReadArchivedTeachers() []Teacher {
+	var teachers []Teacher
+	now := time.Now()
+	for _, t := range readTeachers() {
+		if !t.ArchivedFrom.IsZero() && (t.ArchivedUntil.IsZero() || t.ArchivedUntil.After(now)) {
+			teachers = append(teachers, t)
+		}
+	}
 	return teachers
 }
 
 // Exists tells whether there is a teacher record with this teacher's short.
+// Archived teachers exist as well.
 func (t *Teacher) Exists() bool {
 	var count int
 	db.Get(&count, "SELECT count(*) FROM teachers WHERE short = ?", t.Short)
@@ -34,33 +116,67 @@ func (t *Teacher) Create() {
 	if !t.Exists() {
 		// This teacher (teacher with this short) is not in the database
 		// already, so it is inserted now.
-		stmt := `INSERT INTO teachers(short, name, sex) VALUES (?, ?, ?)`
-		db.Exec(stmt, t.Short, t.Name, t.Sex)
+		stmt := `INSERT INTO teachers(short, name, sex, inactive) VALUES (?, ?, ?, ?)`
+		db.Exec(stmt, t.Short, t.Name, t.Sex, t.Inactive)
 	}
 }
 
 // Read completes this teacher with the teacher information associated
 // with this teacher's short.
 func (t *Teacher) Read() {
-	db.Get(t, "SELECT short, name, sex FROM teachers WHERE short = ?", t.Short)
+	var row teacherRow
+	if err := db.Get(&row, `SELECT `+teacherColumns+` FROM teachers WHERE short = ?`, t.Short); err == nil {
+		*t = row.teacher()
+	}
 }
 
 // Update updates the teacher record with the same short as this teacher's short
-// with the new data. To change the short itself, use UpdateShort.
+// with the new data. To change the short itself, use UpdateShort. The teacher
+// stays active or inactive, see SetInactive.
 func (t *Teacher) Update() {
 	stmt := `UPDATE teachers SET name = ?, sex = ? WHERE short = ?`
 	db.Exec(stmt, t.Name, t.Sex, t.Short)
 }
 
 // UpdateShort updates the teacher identified by the given short with the
-// data included in the given teacher receiver.
-func (t *Teacher) UpdateShort(short string) {
-	stmt := `UPDATE teachers SET short = ?, name = ?, sex = ? WHERE short = ?`
-	db.Exec(stmt, t.Short, t.Name, t.Sex, short)
+// data included in the given teacher receiver. If the short changes, the old
+// short is kept as alias and the rename is recorded, see ReadRenames.
+// ErrShortTaken is returned if the new short is another teacher's short or
+// alias.
+func (t *Teacher) UpdateShort(short string) error {
+	return renameShort(AliasTeacher, short, t.Short, func(tx *sqlx.Tx) error {
+		stmt := `UPDATE teachers SET short = ?, name = ?, sex = ? WHERE short = ?`
+		_, err := tx.Exec(stmt, t.Short, t.Name, t.Sex, short)
+		return err
+	})
+}
+
+// SetInactive marks this teacher as inactive or active again.
+func (t *Teacher) SetInactive(inactive bool) {
+	t.Inactive = inactive
+	stmt := `UPDATE teachers SET inactive = ? WHERE short = ?`
+	db.Exec(stmt, t.Inactive, t.Short)
+}
+
+// Archive moves this teacher to the trash from the given time until the
+// given time, e.g. for a parental leave. With zero until the teacher stays in
+// the trash until restored or purged, see PurgeArchived. Archived teachers
+// are still resolved in plans.
+func (t *Teacher) Archive(from, until time.Time) {
+	t.ArchivedFrom, t.ArchivedUntil = from, until
+	archive("teachers", t.Short, from, until)
+}
+
+// Restore takes this teacher out of the trash.
+func (t *Teacher) Restore() {
+	t.ArchivedFrom, t.ArchivedUntil = time.Time{}, time.Time{}
+	archive("teachers", t.Short, time.Time{}, time.Time{})
 }
 
-// Delete removes this teacher from the database.
+// Delete removes this teacher and the teacher's aliases from the database
+// for good. To keep the teacher in the trash, use Archive.
 func (t *Teacher) Delete() {
 	stmt := `DELETE FROM teachers WHERE short = ?`
 	db.Exec(stmt, t.Short)
+	db.Exec(`DELETE FROM aliases WHERE kind = ? AND short = ?`, AliasTeacher, t.Short)
 }
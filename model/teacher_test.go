@@ -144,3 +144,31 @@ func TestTeacherDelete(t *testing.T) {
 		t.Error("Teacher still exists after deletion.")
 	}
 }
+
+func TestTeacherSetInactive(t *testing.T) {
+	teacher := Teacher{Short: "In", Name: "Inaktiv", Sex: "w"}
+	teacher.Create()
+	defer teacher.Delete()
+
+	teacher.SetInactive(true)
+	teacher = Teacher{Short: "In"}
+	teacher.Read()
+	if !teacher.Inactive {
+		t.Error("Teacher wasn't marked inactive.")
+	}
+
+	// Updates keep the teacher inactive.
+	teacher.Name = "Aktiv"
+	teacher.Inactive = false
+	teacher.Update()
+	teacher.Read()
+	if !teacher.Inactive || teacher.Name != "Aktiv" {
+		t.Errorf("Teacher was read as %+v after update.", teacher)
+	}
+
+	teacher.SetInactive(false)
+	teacher.Read()
+	if teacher.Inactive {
+		t.Error("Teacher wasn't marked active again.")
+	}
+}
@@ -0,0 +1,99 @@
+package model
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"io"
+	"log"
+	"time"
+)
+
+// APIToken grants other programs, e.g. the school administration software,
+// access to the API. Only a hash of the secret is stored, the secret itself is
+// shown once when the token is created.
+type APIToken struct {
+	ID   int64
+	Name string
+	// Prefix is the beginning of the secret to recognize the token by.
+	Prefix   string
+	Created  time.Time
+	LastUsed time.Time
+}
+
+const api_token_schema = `CREATE TABLE api_tokens (name TEXT, hash TEXT UNIQUE, prefix TEXT, created DATETIME, last_used DATETIME)`
+
+type apiTokenRow struct {
+	ID       int64
+	Name     string
+	Prefix   string
+	Created  time.Time
+	LastUsed sql.NullTime `db:"last_used"`
+}
+
+func (row apiTokenRow) token() APIToken {
+	return APIToken{ID: row.ID, Name: row.Name, Prefix: row.Prefix, Created: row.Created, LastUsed: row.LastUsed.Time}
+}
+
+// ReadAllAPITokens fetches all API tokens from the database.
+func ReadAllAPITokens() []APIToken {
+	var rows []apiTokenRow
+	db.Select(&rows, `SELECT rowid AS id, name, prefix, created, last_used FROM api_tokens ORDER BY created asc`)
+
+	tokens := make([]APIToken, len(rows))
+	for i, row := range rows {
+		tokens[i] = row.token()
+	}
+	return tokens
+}
+
+// CreateAPIToken creates a new token with the name and returns it together
+// with its secret.
+func CreateAPIToken(name string) (token APIToken, secret string) {
+	b := make([]byte, 32)
+	if _, err := io.ReadFull(rand.Reader, b); err != nil {
+		log.Fatal(err)
+	}
+	secret = base64.RawURLEncoding.EncodeToString(b)
+
+	token = APIToken{Name: name, Prefix: secret[:8], Created: time.Now()}
+	stmt := `INSERT INTO api_tokens(name, hash, prefix, created) VALUES (?, ?, ?, ?)`
+	if result, err := db.Exec(stmt, token.Name, hashSecret(secret), token.Prefix, token.Created); err == nil {
+		token.ID, _ = result.LastInsertId()
+	}
+	return token, secret
+}
+
+// AuthenticateAPIToken returns the token with the secret and notes that it
+// was used. ok is false if there is no such token.
+func AuthenticateAPIToken(secret string) (token APIToken, ok bool) {
+	var row apiTokenRow
+	err := db.Get(&row, `SELECT rowid AS id, name, prefix, created, last_used FROM api_tokens WHERE hash = ?`, hashSecret(secret))
+	if err != nil {
+		return APIToken{}, false
+	}
+
+	token = row.token()
+	token.LastUsed = time.Now()
+	db.Exec(`UPDATE api_tokens SET last_used = ? WHERE rowid = ?`, token.LastUsed, token.ID)
+	return token, true
+}
+
+// Exists tells whether there is a token with this token's id.
+func (t *APIToken) Exists() bool {
+	var count int
+	db.Get(&count, "SELECT count(*) FROM api_tokens WHERE rowid = ?", t.ID)
+	return count > 0
+}
+
+// Delete removes this token from the database. It can't be used anymore.
+func (t *APIToken) Delete() {
+	db.Exec(`DELETE FROM api_tokens WHERE rowid = ?`, t.ID)
+}
+
+func hashSecret(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,23 @@
+package model
+
+import "testing"
+
+func TestAPIToken(t *testing.T) {
+	token, secret := CreateAPIToken("Verwaltung")
+	if !token.Exists() || len(secret) < 32 || token.Prefix != secret[:8] {
+		t.Fatalf("Unexpected token %+v with secret %q.", token, secret)
+	}
+
+	authenticated, ok := AuthenticateAPIToken(secret)
+	if !ok || authenticated.ID != token.ID || authenticated.LastUsed.IsZero() {
+		t.Errorf("Token wasn't authenticated: %+v, %v", authenticated, ok)
+	}
+	if _, ok := AuthenticateAPIToken(secret + "x"); ok {
+		t.Error("Wrong secret was authenticated.")
+	}
+
+	token.Delete()
+	if _, ok := AuthenticateAPIToken(secret); ok {
+		t.Error("Deleted token was authenticated.")
+	}
+}
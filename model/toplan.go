@@ -1,33 +1,56 @@
 package model
 
 import (
+	"bytes"
 	"encoding/xml"
 	"errors"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"log"
 	"regexp"
+	"strconv"
 	"strings"
 	"time"
-
-	"golang.org/x/text/encoding/charmap"
 )
 
 func ToPlan(uploadReader io.Reader) (*Plan, error) {
-	plan, err := decodePlan(uploadReader)
+	upload, err := ioutil.ReadAll(uploadReader)
+	if err != nil {
+		return &Plan{}, err
+	}
+
+	text, encoding := DecodeText(upload)
+	plan, err := decodePlan(bytes.NewReader(text))
 	if err != nil {
 		return plan, err
 	}
+	plan.Encoding = encoding
+
+	// Untis either puts the page number into the day's title or into the
+	// page footer. Fall back to the footer if the titles don't tell.
+	page, pages := readPageNumber(string(text))
+	for i := range plan.Parts {
+		if plan.Parts[i].pages == 0 {
+			plan.Parts[i].page, plan.Parts[i].pages = page, pages
+		}
+	}
 
 	refine(plan)
+	for i := range plan.Parts {
+		plan.Parts[i].identify()
+	}
 	return plan, nil
 }
 
+// decodePlan reads the plan from an UTF-8 encoded upload.
 func decodePlan(uploadReader io.Reader) (*Plan, error) {
-	encReader := charmap.ISO8859_1.NewDecoder().Reader(uploadReader)
-
-	decoder := xml.NewDecoder(encReader)
+	decoder := xml.NewDecoder(uploadReader)
 	decoder.Entity = xml.HTMLEntity
+	// The upload has already been converted to UTF-8, whatever it declares.
+	decoder.CharsetReader = func(charset string, input io.Reader) (io.Reader, error) {
+		return input, nil
+	}
 
 	if err := moveToNext("font", true, decoder); err != nil {
 		err = errors.New(fmt.Sprintf("Error searching for first \"font\": %v\n", err))
@@ -50,18 +73,20 @@ func decodePlan(uploadReader io.Reader) (*Plan, error) {
 	dayString := string(charData)
 	dayFormat := "2.1.2006"
 	day1, _ := time.ParseInLocation(dayFormat, strings.Split(dayString, " ")[0], loc)
+	page1, pages1 := readPageNumber(dayString)
+	var news1 []string
 
 	if err = moveToNext("table", true, decoder); err != nil {
 		err = errors.New(fmt.Sprintf("Error searching for first \"table\": %v\n", err))
 		log.Println(err)
 		return &Plan{}, err
 	}
-	if err = moveToNext("table", true, decoder); err != nil {
+	if err = moveToNextTable(decoder, &news1); err != nil {
 		err = errors.New(fmt.Sprintf("Error searching for second \"table\": %v\n", err))
 		log.Println(err)
 		return &Plan{}, err
 	}
-	if err = moveToNext("table", true, decoder); err != nil {
+	if err = moveToNextTable(decoder, &news1); err != nil {
 		err = errors.New(fmt.Sprintf("Error searching for third \"table\": %v\n", err))
 		log.Println(err)
 		return &Plan{}, err
@@ -94,7 +119,14 @@ func decodePlan(uploadReader io.Reader) (*Plan, error) {
 		_, ok = token.(xml.StartElement)
 	}
 
-	if err = moveToNext("div", true, decoder); err != nil {
+	firstPart := Part{Day: day1, News: news1, Substitutions: firstPartSubstitutions, page: page1, pages: pages1}
+
+	// Pages of a multi-page export may only contain one day.
+	if err = moveToNext("div", true, decoder); err == io.EOF {
+		return &Plan{
+			Created: created,
+			Parts:   []Part{firstPart}}, nil
+	} else if err != nil {
 		err = errors.New(fmt.Sprintf("Error searching for \"div\" with day of second part: %v\n", err))
 		log.Println(err)
 		return &Plan{}, err
@@ -103,18 +135,20 @@ func decodePlan(uploadReader io.Reader) (*Plan, error) {
 	charData, _ = token.(xml.CharData)
 	dayString = string(charData)
 	day2, _ := time.ParseInLocation(dayFormat, strings.Split(dayString, " ")[0], loc)
+	page2, pages2 := readPageNumber(dayString)
+	var news2 []string
 
 	if err = moveToNext("table", true, decoder); err != nil {
 		err = errors.New(fmt.Sprintf("Error searching for fourth \"table\": %v\n", err))
 		log.Println(err)
 		return &Plan{}, err
 	}
-	if err = moveToNext("table", true, decoder); err != nil {
+	if err = moveToNextTable(decoder, &news2); err != nil {
 		err = errors.New(fmt.Sprintf("Error searching for fifth \"table\": %v\n", err))
 		log.Println(err)
 		return &Plan{}, err
 	}
-	if err = moveToNext("table", true, decoder); err != nil {
+	if err = moveToNextTable(decoder, &news2); err != nil {
 		err = errors.New(fmt.Sprintf("Error searching for sixth \"table\": %v\n", err))
 		log.Println(err)
 		return &Plan{}, err
@@ -148,13 +182,29 @@ func decodePlan(uploadReader io.Reader) (*Plan, error) {
 	}
 
 	parts := []Part{
-		Part{Day: day1, Substitutions: firstPartSubstitutions},
-		Part{Day: day2, Substitutions: secondPartSubstitutions}}
+		firstPart,
+		Part{Day: day2, News: news2, Substitutions: secondPartSubstitutions, page: page2, pages: pages2}}
 	return &Plan{
 		Created: created,
 		Parts:   parts}, nil
 }
 
+// pageNumberRegexp matches the page information Untis adds when a day is
+// split across several pages, e.g. "Seite 1 / 3".
+var pageNumberRegexp = regexp.MustCompile(`Seite\s*(\d+)\s*/\s*(\d+)`)
+
+// readPageNumber extracts the page number and the total of pages from s.
+// Zero values are returned if s doesn't contain page information.
+func readPageNumber(s string) (page, pages int) {
+	match := pageNumberRegexp.FindStringSubmatch(s)
+	if match == nil {
+		return 0, 0
+	}
+	page, _ = strconv.Atoi(match[1])
+	pages, _ = strconv.Atoi(match[2])
+	return page, pages
+}
+
 func readSubstitution(decoder *xml.Decoder) Substitution {
 	var (
 		class             = readInformation(decoder) // Read class.
@@ -192,6 +242,57 @@ func readInformation(decoder *xml.Decoder) string {
 	return information
 }
 
+// moveToNextTable moves to the start of the next table like moveToNext does.
+// On its way it collects the daily news: the texts of the cells of class "info".
+func moveToNextTable(decoder *xml.Decoder, news *[]string) error {
+	var text []string
+	depth := 0
+	for {
+		token, err := decoder.RawToken()
+		if err != nil {
+			return err
+		}
+		switch t := token.(type) {
+		case xml.StartElement:
+			if t.Name.Local == "table" {
+				return nil
+			}
+			if depth > 0 {
+				depth++
+			} else if t.Name.Local == "td" && hasClass(t, "info") {
+				depth = 1
+			}
+		case xml.EndElement:
+			if depth > 0 {
+				depth--
+				if depth == 0 {
+					if line := strings.Join(strings.Fields(strings.Join(text, " ")), " "); line != "" {
+						*news = append(*news, line)
+					}
+					text = nil
+				}
+			}
+		case xml.CharData:
+			if depth > 0 {
+				text = append(text, string(t))
+			}
+		}
+	}
+}
+
+func hasClass(element xml.StartElement, class string) bool {
+	for _, attr := range element.Attr {
+		if attr.Name.Local == "class" {
+			for _, c := range strings.Fields(attr.Value) {
+				if c == class {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
 func moveToNext(elementName string, se bool, decoder *xml.Decoder) error {
 	for {
 		token, err := decoder.RawToken()
@@ -214,8 +315,34 @@ func moveToNext(elementName string, se bool, decoder *xml.Decoder) error {
 	}
 }
 
+// refine cleans up the plan read from the file. Only the shorts of teachers
+// and subjects are kept, their names are added when the plan is served.
+// See Plan.Resolve. Aliases are replaced by the shorts they stand for.
 func refine(plan *Plan) {
 	const nbsp = "\u00A0"
+	aliases := readAliases()
+
+	// Shorts in texts are validated against the teacher table. Their case
+	// may differ from the teacher's short.
+	shorts := make(map[string]string)
+	for alias, short := range aliases[AliasTeacher] {
+		shorts[strings.ToLower(alias)] = short
+	}
+	for _, t := range readTeachers() {
+		shorts[strings.ToLower(t.Short)] = t.Short
+		// Teachers win over aliases with their short.
+		delete(aliases[AliasTeacher], t.Short)
+	}
+	for _, s := range readSubjects() {
+		delete(aliases[AliasSubject], s.Short)
+	}
+	teacher := func(candidate string) (string, bool) {
+		short, ok := shorts[strings.ToLower(candidate)]
+		return short, ok
+	}
+	// Noise like "???" for a missing substitute is removed.
+	ignored := ignoredShort()
+
 	for p, part := range plan.Parts {
 		for s, substitution := range part.Substitutions {
 			if substitution.Period == nbsp {
@@ -224,21 +351,23 @@ func refine(plan *Plan) {
 			if substitution.Class == nbsp {
 				plan.Parts[p].Substitutions[s].Class = ""
 			}
-			if substitution.SubstTeacher.Short == nbsp || substitution.SubstTeacher.Short == "???" ||
-				substitution.SubstTeacher.Short == "+" || substitution.SubstTeacher.Short == "---" {
+			if substitution.SubstTeacher.Short == nbsp || ignored(substitution.SubstTeacher.Short) {
 				plan.Parts[p].Substitutions[s].SubstTeacher.Short = ""
-			} else {
-				plan.Parts[p].Substitutions[s].SubstTeacher.Read()
 			}
-			if substitution.InstdTeacher.Short == nbsp {
+			if substitution.InstdTeacher.Short == nbsp || ignored(substitution.InstdTeacher.Short) {
 				plan.Parts[p].Substitutions[s].InstdTeacher.Short = ""
-			} else {
-				plan.Parts[p].Substitutions[s].InstdTeacher.Read()
 			}
-			if substitution.InstdSubject.Short == nbsp {
+			if substitution.InstdSubject.Short == nbsp || ignored(substitution.InstdSubject.Short) {
 				plan.Parts[p].Substitutions[s].InstdSubject.Short = ""
-			} else {
-				plan.Parts[p].Substitutions[s].InstdSubject.Read()
+			}
+			if short, ok := aliases[AliasTeacher][substitution.SubstTeacher.Short]; ok {
+				plan.Parts[p].Substitutions[s].SubstTeacher.Short = short
+			}
+			if short, ok := aliases[AliasTeacher][substitution.InstdTeacher.Short]; ok {
+				plan.Parts[p].Substitutions[s].InstdTeacher.Short = short
+			}
+			if short, ok := aliases[AliasSubject][substitution.InstdSubject.Short]; ok {
+				plan.Parts[p].Substitutions[s].InstdSubject.Short = short
 			}
 			if substitution.Kind == nbsp {
 				plan.Parts[p].Substitutions[s].Kind = ""
@@ -248,14 +377,17 @@ func refine(plan *Plan) {
 			if substitution.Text == nbsp {
 				plan.Parts[p].Substitutions[s].Text = ""
 			} else {
-				re := regexp.MustCompile("Aufg(\\.|(abe)) [A-Za-z]{2,3}")
-				task := re.FindString(substitution.Text)
-				if task != "" {
-					provider := strings.Trim(task[len(task)-3:], " ")
-					taskProvider := Teacher{Short: provider}
-					taskProvider.Read()
-					plan.Parts[p].Substitutions[s].TaskProvider = taskProvider
+				annotations := parseText(substitution.Text, teacher)
+				for _, short := range annotations.TaskProviders {
+					taskProvider := Teacher{Short: short}
+					plan.Parts[p].Substitutions[s].TaskProviders = append(plan.Parts[p].Substitutions[s].TaskProviders, taskProvider)
+				}
+				if len(annotations.TaskProviders) > 0 {
+					plan.Parts[p].Substitutions[s].TaskProvider = plan.Parts[p].Substitutions[s].TaskProviders[0]
 				}
+				plan.Parts[p].Substitutions[s].Rooms = annotations.Rooms
+				plan.Parts[p].Substitutions[s].MovedTo = annotations.MovedTo
+				plan.Parts[p].Substitutions[s].MovedFrom = annotations.MovedFrom
 			}
 		}
 	}
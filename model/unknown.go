@@ -1,42 +1,258 @@
 package model
 
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// maxUnknownSamples is the number of substitutions kept per unknown short.
+const maxUnknownSamples = 3
+
+// UnknownTeacher is a short found in a plan which isn't a teacher's short.
 type UnknownTeacher struct {
-	Short string
+	Short     string
+	FirstSeen time.Time
+	LastSeen  time.Time
+	// Count is the number of substitutions the short was found in.
+	Count int
+	// Samples describe the latest substitutions the short was found in.
+	Samples []string
+	// Ignored shorts aren't listed as unknown.
+	Ignored bool
+	// SubstitutionIDs are the IDs of the substitutions counted, so each
+	// substitution is counted once however often it is uploaded.
+	SubstitutionIDs []string
 }
 
 const unknown_schema = "CREATE TABLE unknown_teachers (short TEXT UNIQUE);CREATE TABLE unknown_subjects (short TEXT UNIQUE)"
 
+// unknownColumns are the columns added to both unknown tables.
+var unknownColumns = [][2]string{
+	{"first_seen", "DATETIME"},
+	{"last_seen", "DATETIME"},
+	{"count", "INTEGER NOT NULL DEFAULT 0"},
+	{"samples", "TEXT"},
+	{"ignored", "BOOLEAN NOT NULL DEFAULT 0"},
+	{"substitution_ids", "TEXT"},
+}
+
+// unknown is the common part of UnknownTeacher and UnknownSubject.
+type unknown struct {
+	Short           string
+	FirstSeen       time.Time
+	LastSeen        time.Time
+	Count           int
+	Samples         []string
+	Ignored         bool
+	SubstitutionIDs []string
+}
+
+type unknownRow struct {
+	Short           string
+	FirstSeen       sql.NullTime `db:"first_seen"`
+	LastSeen        sql.NullTime `db:"last_seen"`
+	Count           int
+	Samples         sql.NullString
+	Ignored         bool
+	SubstitutionIDs sql.NullString `db:"substitution_ids"`
+}
+
+func (row unknownRow) unknown() unknown {
+	u := unknown{Short: row.Short, FirstSeen: row.FirstSeen.Time, LastSeen: row.LastSeen.Time,
+		Count: row.Count, Ignored: row.Ignored}
+	if row.Samples.String != "" {
+		u.Samples = strings.Split(row.Samples.String, "\n")
+	}
+	if row.SubstitutionIDs.String != "" {
+		u.SubstitutionIDs = strings.Split(row.SubstitutionIDs.String, "\n")
+	}
+	return u
+}
+
+func readUnknowns(table string, ignored bool) []unknown {
+	var rows []unknownRow
+	db.Select(&rows, `SELECT short, first_seen, last_seen, count, samples, ignored, substitution_ids FROM `+table+`
+		WHERE ignored = ? ORDER BY short asc`, ignored)
+
+	unknowns := make([]unknown, len(rows))
+	for i, row := range rows {
+		unknowns[i] = row.unknown()
+	}
+	return unknowns
+}
+
+func readUnknown(table, short string) (unknown, bool) {
+	var row unknownRow
+	err := db.Get(&row, `SELECT short, first_seen, last_seen, count, samples, ignored, substitution_ids FROM `+table+`
+		WHERE short = ?`, short)
+	return row.unknown(), err == nil
+}
+
+// recordUnknown adds the short to the table or, if it is recorded already,
+// counts it once more and adds the sample. A substitution counted already,
+// known by its id, only updates the time the short was last seen.
+func recordUnknown(table, short, id, sample string, seen time.Time) {
+	u, ok := readUnknown(table, short)
+	if !ok {
+		db.Exec(`INSERT INTO `+table+` (short, first_seen, last_seen, count, samples, substitution_ids) VALUES (?, ?, ?, ?, ?, ?)`,
+			short, seen, seen, 1, sample, id)
+		return
+	}
+	if u.FirstSeen.IsZero() {
+		u.FirstSeen = seen
+	}
+	for _, counted := range u.SubstitutionIDs {
+		if id != "" && counted == id {
+			db.Exec(`UPDATE `+table+` SET first_seen = ?, last_seen = ? WHERE short = ?`, u.FirstSeen, seen, short)
+			return
+		}
+	}
+
+	samples := []string{}
+	if sample != "" {
+		samples = append(samples, sample)
+	}
+	for _, s := range u.Samples {
+		if s != sample && len(samples) < maxUnknownSamples {
+			samples = append(samples, s)
+		}
+	}
+	if id != "" {
+		u.SubstitutionIDs = append(u.SubstitutionIDs, id)
+	}
+	db.Exec(`UPDATE `+table+` SET first_seen = ?, last_seen = ?, count = ?, samples = ?, substitution_ids = ? WHERE short = ?`,
+		u.FirstSeen, seen, u.Count+1, strings.Join(samples, "\n"), strings.Join(u.SubstitutionIDs, "\n"), short)
+}
+
+// restoreUnknown inserts the unknown short as it was recorded before.
+func restoreUnknown(table string, u unknown) {
+	db.Exec(`INSERT INTO `+table+` (short, first_seen, last_seen, count, samples, ignored, substitution_ids)
+		VALUES (?, ?, ?, ?, ?, ?, ?)`,
+		u.Short, u.FirstSeen, u.LastSeen, u.Count, strings.Join(u.Samples, "\n"), u.Ignored, strings.Join(u.SubstitutionIDs, "\n"))
+}
+
+// RecordUnknowns records the shorts of the plan which are neither teachers' or
+// subjects' shorts nor their aliases, each with the substitution it was found
+// in as sample. Each substitution is counted once, see Substitution.ID.
+func RecordUnknowns(plan *Plan, seen time.Time) {
+	teachers, subjects := readDirectory()
+	for _, part := range plan.Parts {
+		for _, s := range part.Substitutions {
+			sample := fmt.Sprintf("%s, Klasse %s, %s. Stunde", part.Day.Format("02.01.2006"), s.Class, s.Period)
+			for _, short := range []string{s.SubstTeacher.Short, s.InstdTeacher.Short} {
+				if _, known := teachers[short]; !known && short != "" {
+					recordUnknown("unknown_teachers", short, s.ID, sample, seen)
+				}
+			}
+			short := s.InstdSubject.Short
+			if _, known := subjects[short]; !known && short != "" {
+				recordUnknown("unknown_subjects", short, s.ID, sample, seen)
+			}
+		}
+	}
+}
+
+// Create records this short as unknown teacher.
 func (ut *UnknownTeacher) Create() {
-	stmt := `INSERT INTO unknown_teachers (short) VALUES (?)`
-	db.Exec(stmt, ut.Short)
+	recordUnknown("unknown_teachers", ut.Short, "", "", time.Now())
 }
 
+// ReadAllUnknownTeachers returns the unknown teachers which aren't ignored.
 func ReadAllUnknownTeachers() []UnknownTeacher {
 	var unknownTeachers []UnknownTeacher
-	db.Select(&unknownTeachers, "SELECT short FROM unknown_teachers")
+	for _, u := range readUnknowns("unknown_teachers", false) {
+		unknownTeachers = append(unknownTeachers, UnknownTeacher(u))
+	}
+	return unknownTeachers
+}
+
+// ReadIgnoredUnknownTeachers returns the ignored unknown teachers.
+func ReadIgnoredUnknownTeachers() []UnknownTeacher {
+	var unknownTeachers []UnknownTeacher
+	for _, u := range readUnknowns("unknown_teachers", true) {
+		unknownTeachers = append(unknownTeachers, UnknownTeacher(u))
+	}
 	return unknownTeachers
 }
 
+// Read completes this unknown teacher. It tells whether the short is
+// recorded as unknown.
+func (ut *UnknownTeacher) Read() bool {
+	u, ok := readUnknown("unknown_teachers", ut.Short)
+	if ok {
+		*ut = UnknownTeacher(u)
+	}
+	return ok
+}
+
+// SetIgnored stops or starts listing this short as unknown.
+func (ut *UnknownTeacher) SetIgnored(ignored bool) {
+	ut.Ignored = ignored
+	db.Exec(`UPDATE unknown_teachers SET ignored = ? WHERE short = ?`, ignored, ut.Short)
+}
+
 func (ut *UnknownTeacher) Delete() {
 	stmt := `DELETE FROM unknown_teachers WHERE short = ?`
 	db.Exec(stmt, ut.Short)
 }
 
+// UnknownSubject is a short found in a plan which isn't a subject's short.
 type UnknownSubject struct {
-	Short string
+	Short     string
+	FirstSeen time.Time
+	LastSeen  time.Time
+	// Count is the number of substitutions the short was found in.
+	Count int
+	// Samples describe the latest substitutions the short was found in.
+	Samples []string
+	// Ignored shorts aren't listed as unknown.
+	Ignored bool
+	// SubstitutionIDs are the IDs of the substitutions counted, so each
+	// substitution is counted once however often it is uploaded.
+	SubstitutionIDs []string
 }
 
+// Create records this short as unknown subject.
 func (us *UnknownSubject) Create() {
-	stmt := `INSERT INTO unknown_subjects (short) VALUES (?)`
-	db.Exec(stmt, us.Short)
+	recordUnknown("unknown_subjects", us.Short, "", "", time.Now())
 }
 
+// ReadAllUnknownSubjects returns the unknown subjects which aren't ignored.
 func ReadAllUnknownSubjects() []UnknownSubject {
 	var unknownSubjects []UnknownSubject
-	db.Select(&unknownSubjects, "SELECT short FROM unknown_subjects")
+	for _, u := range readUnknowns("unknown_subjects", false) {
+		unknownSubjects = append(unknownSubjects, UnknownSubject(u))
+	}
+	return unknownSubjects
+}
+
+// ReadIgnoredUnknownSubjects returns the ignored unknown subjects.
+func ReadIgnoredUnknownSubjects() []UnknownSubject {
+	var unknownSubjects []UnknownSubject
+	for _, u := range readUnknowns("unknown_subjects", true) {
+		unknownSubjects = append(unknownSubjects, UnknownSubject(u))
+	}
 	return unknownSubjects
 }
 
+// Read completes this unknown subject. It tells whether the short is
+// recorded as unknown.
+func (us *UnknownSubject) Read() bool {
+	u, ok := readUnknown("unknown_subjects", us.Short)
+	if ok {
+		*us = UnknownSubject(u)
+	}
+	return ok
+}
+
+// SetIgnored stops or starts listing this short as unknown.
+func (us *UnknownSubject) SetIgnored(ignored bool) {
+	us.Ignored = ignored
+	db.Exec(`UPDATE unknown_subjects SET ignored = ? WHERE short = ?`, ignored, us.Short)
+}
+
 func (us *UnknownSubject) Delete() {
 	stmt := `DELETE FROM unknown_subjects WHERE short = ?`
 	db.Exec(stmt, us.Short)
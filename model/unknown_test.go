@@ -0,0 +1,96 @@
+package model
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRecordUnknowns(t *testing.T) {
+	known := Teacher{Short: "UkA", Name: "Bekannt", Sex: "m"}
+	known.Create()
+	alias := Alias{Kind: AliasTeacher, Alias: "UkAl", Short: "UkA"}
+	alias.Create()
+	defer known.Delete()
+	defer alias.Delete()
+
+	day := time.Date(2016, 10, 19, 0, 0, 0, 0, time.UTC)
+	plan := &Plan{Parts: []Part{{Day: day, Substitutions: []Substitution{
+		{Class: "5a", Period: "1", SubstTeacher: Teacher{Short: "UkX"}, InstdTeacher: Teacher{Short: "UkA"}, InstdSubject: Subject{Short: "UkS"}},
+		{Class: "5b", Period: "2", SubstTeacher: Teacher{Short: "UkAl"}, InstdTeacher: Teacher{Short: "UkX"}},
+	}}}}
+	plan.Parts[0].identify()
+	first := time.Date(2016, 10, 18, 7, 0, 0, 0, time.UTC)
+	RecordUnknowns(plan, first)
+	// Uploading the same substitutions again doesn't count them again.
+	RecordUnknowns(plan, first.Add(time.Hour))
+
+	unknown := UnknownTeacher{Short: "UkX"}
+	if !unknown.Read() {
+		t.Fatal("Unknown teacher wasn't recorded.")
+	}
+	if unknown.Count != 2 || !unknown.FirstSeen.Equal(first) || !unknown.LastSeen.Equal(first.Add(time.Hour)) {
+		t.Errorf("Unexpected unknown teacher %+v.", unknown)
+	}
+	if len(unknown.Samples) != 2 || unknown.Samples[0] != "19.10.2016, Klasse 5b, 2. Stunde" {
+		t.Errorf("Unexpected samples %q.", unknown.Samples)
+	}
+	for _, short := range []string{"UkA", "UkAl", ""} {
+		u := UnknownTeacher{Short: short}
+		if u.Read() {
+			t.Errorf("%q was recorded as unknown.", short)
+		}
+	}
+
+	unknown.SetIgnored(true)
+	for _, u := range ReadAllUnknownTeachers() {
+		if u.Short == "UkX" {
+			t.Error("Ignored teacher is listed as unknown.")
+		}
+	}
+	unknown.Delete()
+	subject := UnknownSubject{Short: "UkS"}
+	if !subject.Read() || subject.Count != 1 {
+		t.Errorf("Unexpected unknown subject %+v.", subject)
+	}
+	subject.Delete()
+}
+
+func TestSuggest(t *testing.T) {
+	names := map[string]string{"MÜ": "Müller", "Mr": "Maier", "Sp": "Sport", "Sz": "Schulz"}
+	suggestions := suggest("MUE", names, nil)
+	if len(suggestions) != 1 || suggestions[0].Short != "MÜ" || suggestions[0].Reason != "andere Schreibweise" {
+		t.Errorf("Unexpected suggestions %+v.", suggestions)
+	}
+
+	suggestions = suggest("SP", names, nil)
+	if len(suggestions) != 2 || suggestions[0].Short != "Sp" || suggestions[1].Short != "Sz" {
+		t.Errorf("Unexpected suggestions %+v.", suggestions)
+	}
+
+	suggestions = suggest("sch", names, map[string]string{"Sch": "Sz"})
+	if len(suggestions) != 1 || suggestions[0].Short != "Sz" || suggestions[0].Reason != "früher Sch" {
+		t.Errorf("Unexpected suggestions %+v.", suggestions)
+	}
+}
+
+func TestIgnorePatterns(t *testing.T) {
+	if ignored := ignoredShort(); !ignored("???") || !ignored("+") || ignored("Md") || ignored("") {
+		t.Error("Default patterns don't match as expected.")
+	}
+
+	invalid := IgnorePattern{Pattern: "(["}
+	if invalid.Create() == nil {
+		t.Error("Invalid pattern was created.")
+	}
+	pattern := IgnorePattern{Pattern: "^N\\.N\\.$"}
+	if err := pattern.Create(); err != nil {
+		t.Fatal(err)
+	}
+	if !ignoredShort()("N.N.") {
+		t.Error("New pattern doesn't match.")
+	}
+	pattern.Delete()
+	if pattern.Exists() {
+		t.Error("Pattern wasn't deleted.")
+	}
+}
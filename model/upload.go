@@ -0,0 +1,226 @@
+package model
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"sort"
+	"time"
+)
+
+// ErrNoFile is returned when the file of an upload was already pruned.
+var ErrNoFile = errors.New("model: upload has no file")
+
+// PlanUpload describes a stored plan upload without the plan itself.
+type PlanUpload struct {
+	ID int64
+	// Time is the time of the upload, Confirmed the time it was last uploaded
+	// again without changes.
+	Time      time.Time
+	Confirmed time.Time
+	Hash      string
+	Encoding  string
+	HasFile   bool
+	// Reprocessed tells whether the plan was read again from the file. The
+	// plan first read is kept as previous plan.
+	Reprocessed bool
+	// Modified is the time the plan was last stored, the time of the upload or
+	// of the last reprocessing.
+	Modified time.Time
+}
+
+const planUploadColumns = `id, upload, confirmed, hash, encoding,
+	file IS NOT NULL AS hasfile, previous_json IS NOT NULL AS reprocessed, reprocessed_at`
+
+type planUploadRow struct {
+	ID            int64
+	Upload        time.Time
+	Confirmed     sql.NullTime
+	Hash          sql.NullString
+	Encoding      sql.NullString
+	HasFile       bool
+	Reprocessed   bool
+	ReprocessedAt sql.NullTime `db:"reprocessed_at"`
+}
+
+func (row planUploadRow) planUpload() PlanUpload {
+	upload := PlanUpload{ID: row.ID, Time: row.Upload, Confirmed: row.Upload, Hash: row.Hash.String,
+		Encoding: row.Encoding.String, HasFile: row.HasFile, Reprocessed: row.Reprocessed, Modified: row.Upload}
+	if row.Confirmed.Valid {
+		upload.Confirmed = row.Confirmed.Time
+	}
+	if row.ReprocessedAt.Time.After(upload.Modified) {
+		upload.Modified = row.ReprocessedAt.Time
+	}
+	return upload
+}
+
+// ReadAllPlanUploads returns all stored uploads, the newest first.
+func ReadAllPlanUploads() []PlanUpload {
+	var rows []planUploadRow
+	db.Select(&rows, `SELECT `+planUploadColumns+` FROM plans ORDER BY upload DESC`)
+
+	uploads := make([]PlanUpload, len(rows))
+	for i, row := range rows {
+		uploads[i] = row.planUpload()
+	}
+	return uploads
+}
+
+// Exists tells whether there is an upload with this upload's id.
+func (u *PlanUpload) Exists() bool {
+	var count int
+	db.Get(&count, "SELECT count(*) FROM plans WHERE id = ?", u.ID)
+	return count > 0
+}
+
+// Read completes this upload with the information stored for its id.
+func (u *PlanUpload) Read() {
+	var row planUploadRow
+	if err := db.Get(&row, `SELECT `+planUploadColumns+` FROM plans WHERE id = ?`, u.ID); err == nil {
+		*u = row.planUpload()
+	}
+}
+
+// File returns the uploaded file. Multi-page uploads are returned as zip archive.
+func (u *PlanUpload) File() ([]byte, error) {
+	var file []byte
+	if err := db.Get(&file, "SELECT file FROM plans WHERE id = ?", u.ID); err != nil {
+		return nil, err
+	}
+	if file == nil {
+		return nil, ErrNoFile
+	}
+	return file, nil
+}
+
+// Plan returns the plan stored with this upload.
+func (u *PlanUpload) Plan() (*Plan, error) {
+	return u.readPlan("json")
+}
+
+// PreviousPlan returns the plan stored with this upload before it was first
+// reprocessed.
+func (u *PlanUpload) PreviousPlan() (*Plan, error) {
+	return u.readPlan("previous_json")
+}
+
+func (u *PlanUpload) readPlan(column string) (*Plan, error) {
+	var data sql.NullString
+	if err := db.Get(&data, "SELECT "+column+" FROM plans WHERE id = ?", u.ID); err != nil {
+		return &Plan{}, err
+	}
+	plan := &Plan{}
+	if !data.Valid {
+		return plan, sql.ErrNoRows
+	}
+	err := json.Unmarshal([]byte(data.String), plan)
+	return plan, err
+}
+
+// Reprocess reads the plan again from the uploaded file, for example after the
+// plan reader was fixed. The plan first read from the file is kept as previous
+// plan and the changes to the plan stored until now are returned. Unknown
+// shorts aren't recorded again, they were counted when the plan was uploaded.
+func (u *PlanUpload) Reprocess() ([]Change, error) {
+	file, err := u.File()
+	if err != nil {
+		return nil, err
+	}
+	pages, err := UnpackPages(file)
+	if err != nil {
+		return nil, err
+	}
+	plan, err := ToPlanPages(pages)
+	if err != nil {
+		return nil, err
+	}
+	old, err := u.Plan()
+	if err != nil {
+		return nil, err
+	}
+
+	json, _ := json.Marshal(*plan)
+	stmt := `UPDATE plans SET previous_json = COALESCE(previous_json, json), json = ?, hash = ?, encoding = ?,
+		reprocessed_at = ? WHERE id = ?`
+	if _, err = db.Exec(stmt, string(json), plan.Hash(), plan.Encoding, time.Now(), u.ID); err != nil {
+		return nil, err
+	}
+	u.Read()
+	return Compare(old, plan), nil
+}
+
+// LastPlanUpload returns the newest upload. ok is false if there isn't any.
+func LastPlanUpload() (upload PlanUpload, ok bool) {
+	var row planUploadRow
+	if err := db.Get(&row, `SELECT `+planUploadColumns+` FROM plans ORDER BY upload DESC LIMIT 1`); err != nil {
+		return PlanUpload{}, false
+	}
+	return row.planUpload(), true
+}
+
+// History holds the changes an upload brought compared to the upload before.
+type History struct {
+	Upload  PlanUpload
+	Changes []Change
+}
+
+// ReadHistory returns the changes of the newest n uploads, the newest first.
+// The plans are resolved with the current teachers and subjects.
+func ReadHistory(n int) []History {
+	var rows []planUploadRow
+	db.Select(&rows, `SELECT `+planUploadColumns+` FROM plans ORDER BY upload DESC LIMIT ?`, n+1)
+
+	plans := make([]*Plan, len(rows))
+	for i, row := range rows {
+		upload := row.planUpload()
+		if plan, err := upload.Plan(); err == nil {
+			plan.Resolve()
+			plans[i] = plan
+		}
+	}
+
+	var history []History
+	for i := 0; i+1 < len(rows); i++ {
+		if plans[i] == nil || plans[i+1] == nil {
+			continue
+		}
+		history = append(history, History{Upload: rows[i].planUpload(), Changes: Compare(plans[i+1], plans[i])})
+	}
+	return history
+}
+
+// ReadDays returns the days from from to to as read from the last upload
+// listing them, in the order of days. The plans are resolved with the current
+// teachers and subjects.
+func ReadDays(from, to time.Time) []Part {
+	first, last := from.Format("2006-01-02"), to.Format("2006-01-02")
+
+	// Days are listed in the plan a few days in advance at most.
+	earliest, latest := from.AddDate(0, 0, -14), to.AddDate(0, 0, 1)
+
+	days := make(map[string]Part)
+	for _, upload := range ReadAllPlanUploads() {
+		if upload.Time.Before(earliest) || !upload.Time.Before(latest) {
+			continue
+		}
+		plan, err := upload.Plan()
+		if err != nil {
+			continue
+		}
+		plan.Resolve()
+		for _, part := range plan.Parts {
+			day := part.Day.Format("2006-01-02")
+			if _, ok := days[day]; !ok && day >= first && day <= last {
+				days[day] = part
+			}
+		}
+	}
+
+	parts := make([]Part, 0, len(days))
+	for _, part := range days {
+		parts = append(parts, part)
+	}
+	sort.Slice(parts, func(i, j int) bool { return parts[i].Day.Before(parts[j].Day) })
+	return parts
+}
@@ -0,0 +1,107 @@
+package model
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPlanUploadReprocess(t *testing.T) {
+	file := readTestPages(t, "subst.htm")[0]
+	plan, err := ToPlanPages([][]byte{file})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Store the plan as an older reader would have read it.
+	plan.Parts[0].Substitutions[0].Text = ""
+	id, stored, err := plan.Create(file)
+	if err != nil || !stored {
+		t.Fatal("Plan wasn't stored.")
+	}
+
+	upload := PlanUpload{ID: id}
+	upload.Read()
+	if !upload.HasFile || upload.Reprocessed {
+		t.Errorf("Unexpected upload: %+v", upload)
+	}
+	if stored, err := upload.File(); err != nil || string(stored) != string(file) {
+		t.Errorf("File wasn't stored: %v", err)
+	}
+
+	version := PlanVersion()
+	changes, err := upload.Reprocess()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if PlanVersion() == version {
+		t.Error("Plan version didn't change.")
+	}
+	if len(changes) != 1 || changes[0].Kind() != ChangeChanged || changes[0].After.Text != "Aufg. Sz" {
+		t.Errorf("Unexpected changes: %+v", changes)
+	}
+	if !upload.Reprocessed || !upload.Modified.After(upload.Time) {
+		t.Errorf("Upload wasn't marked as reprocessed: %+v", upload)
+	}
+	if last, json := LastPlanJSON(); last.ID != upload.ID || !last.Modified.Equal(upload.Modified) || !strings.Contains(json, "Aufg. Sz") {
+		t.Errorf("Last plan isn't the reprocessed one: %+v", last)
+	}
+
+	previous, err := upload.PreviousPlan()
+	if err != nil || previous.Parts[0].Substitutions[0].Text != "" {
+		t.Errorf("Previous plan wasn't kept: %v", err)
+	}
+
+	// Reprocessing again keeps the plan first read.
+	if _, err := upload.Reprocess(); err != nil {
+		t.Fatal(err)
+	}
+	if previous, _ := upload.PreviousPlan(); previous.Parts[0].Substitutions[0].Text != "" {
+		t.Error("Previous plan was overwritten.")
+	}
+}
+
+func TestReadHistory(t *testing.T) {
+	file := readTestPages(t, "subst.htm")[0]
+	plan, err := ToPlanPages([][]byte{file})
+	if err != nil {
+		t.Fatal(err)
+	}
+	plan.Parts[0].Substitutions[0].Text = "Erste Fassung"
+	plan.Create(file)
+	plan.Parts[0].Substitutions[0].Text = "Zweite Fassung"
+	id, _, _ := plan.Create(file)
+
+	history := ReadHistory(1)
+	if len(history) != 1 || history[0].Upload.ID != id {
+		t.Fatalf("Unexpected history: %+v", history)
+	}
+	changes := history[0].Changes
+	if len(changes) != 1 || changes[0].Kind() != ChangeChanged || changes[0].After.Text != "Zweite Fassung" {
+		t.Errorf("Unexpected changes: %+v", changes)
+	}
+}
+
+func TestReadDays(t *testing.T) {
+	file := readTestPages(t, "subst.htm")[0]
+	plan, err := ToPlanPages([][]byte{file})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Move the plan's days to today and tomorrow.
+	today := time.Now().Truncate(24 * time.Hour)
+	plan.Parts[0].Day, plan.Parts[1].Day = today, today.AddDate(0, 0, 1)
+	plan.Parts[1].Substitutions[0].Text = "Erste Fassung"
+	plan.Create(file)
+	plan.Parts[1].Substitutions[0].Text = "Zweite Fassung"
+	plan.Create(file)
+
+	parts := ReadDays(today.AddDate(0, 0, 1), today.AddDate(0, 0, 7))
+	if len(parts) != 1 || parts[0].Substitutions[0].Text != "Zweite Fassung" {
+		t.Errorf("Unexpected days %+v.", parts)
+	}
+	if parts := ReadDays(today, today.AddDate(0, 0, 1)); len(parts) != 2 || !parts[0].Day.Equal(today) {
+		t.Errorf("Unexpected days %+v.", parts)
+	}
+}
@@ -0,0 +1,109 @@
+package pdf
+
+import (
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// asciiWidths are the widths of the characters from space to "~" in 1/1000
+// of the font size, taken from the Adobe font metrics.
+var asciiWidths = [...][95]int{
+	Helvetica: {
+		278, 278, 355, 556, 556, 889, 667, 191, 333, 333, 389, 584, 278, 333, 278, 278,
+		556, 556, 556, 556, 556, 556, 556, 556, 556, 556, 278, 278, 584, 584, 584, 556,
+		1015, 667, 667, 722, 722, 667, 611, 778, 722, 278, 500, 667, 556, 833, 722, 778,
+		667, 778, 722, 667, 611, 722, 667, 944, 667, 667, 611, 278, 278, 278, 469, 556,
+		333, 556, 556, 500, 556, 556, 278, 556, 556, 222, 222, 500, 222, 833, 556, 556,
+		556, 556, 333, 500, 278, 556, 500, 722, 500, 500, 500, 334, 260, 334, 584,
+	},
+	HelveticaBold: {
+		278, 333, 474, 556, 556, 889, 722, 238, 333, 333, 389, 584, 278, 333, 278, 278,
+		556, 556, 556, 556, 556, 556, 556, 556, 556, 556, 333, 333, 584, 584, 584, 611,
+		975, 722, 722, 722, 722, 667, 611, 778, 722, 278, 556, 722, 611, 833, 722, 778,
+		667, 778, 722, 667, 611, 722, 667, 944, 667, 667, 611, 333, 278, 333, 584, 556,
+		333, 556, 611, 556, 611, 556, 333, 611, 611, 278, 278, 556, 278, 889, 611, 611,
+		611, 611, 389, 556, 333, 611, 556, 778, 556, 556, 500, 389, 280, 389, 584,
+	},
+}
+
+// otherWidths are the widths of characters beyond ASCII which aren't letters
+// with diacritics. These are measured like their base letter.
+var otherWidths = map[rune][2]int{
+	'ß': {611, 611}, 'Æ': {1000, 1000}, 'æ': {889, 889}, 'Ø': {778, 778}, 'ø': {611, 611},
+	'€': {556, 556}, '§': {556, 556}, '°': {400, 400}, '–': {556, 556}, '—': {1000, 1000},
+	'„': {333, 500}, '“': {333, 500}, '”': {333, 500}, '‚': {222, 278}, '‘': {222, 278},
+	'’': {222, 278}, '…': {1000, 1000}, '•': {350, 350}, '«': {556, 556}, '»': {556, 556},
+	'×': {584, 584}, '÷': {584, 584}, '±': {584, 584}, '½': {834, 834}, '¼': {834, 834},
+	'¾': {834, 834}, '²': {333, 333}, '³': {333, 333}, '¹': {333, 333}, '·': {278, 278},
+	' ': {278, 278},
+}
+
+// Width returns the width of the text in points.
+func Width(font Font, size float64, text string) float64 {
+	var width int
+	for _, r := range text {
+		width += runeWidth(font, r)
+	}
+	return float64(width) * size / 1000
+}
+
+func runeWidth(font Font, r rune) int {
+	if r >= ' ' && r <= '~' {
+		return asciiWidths[font][r-' ']
+	}
+	if w, ok := otherWidths[r]; ok {
+		return w[font]
+	}
+	// Measure letters with diacritics like "ä" like their base letter.
+	if base := []rune(norm.NFD.String(string(r))); len(base) > 1 && base[0] >= ' ' && base[0] <= '~' {
+		return asciiWidths[font][base[0]-' ']
+	}
+	return 556
+}
+
+// Wrap breaks the text into lines not wider than width. Words wider than
+// width are broken between characters.
+func Wrap(font Font, size float64, text string, width float64) []string {
+	var lines []string
+	line := ""
+	for _, word := range strings.FieldsFunc(text, unicode.IsSpace) {
+		candidate := word
+		if line != "" {
+			candidate = line + " " + word
+		}
+		if Width(font, size, candidate) <= width {
+			line = candidate
+			continue
+		}
+		if line != "" {
+			lines = append(lines, line)
+		}
+		line = word
+		for Width(font, size, line) > width {
+			cut := fit(font, size, line, width)
+			lines = append(lines, line[:cut])
+			line = line[cut:]
+		}
+	}
+	if line != "" || len(lines) == 0 {
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+// fit returns the length in bytes of the longest beginning of text not wider
+// than width, but at least one character.
+func fit(font Font, size float64, text string, width float64) int {
+	end := 0
+	var w int
+	for i, r := range text {
+		w += runeWidth(font, r)
+		if float64(w)*size/1000 > width && i > 0 {
+			return i
+		}
+		end = i + len(string(r))
+	}
+	return end
+}
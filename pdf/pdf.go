@@ -0,0 +1,181 @@
+// Package pdf writes simple PDF documents with text, lines and filled
+// rectangles. It only uses the standard fonts Helvetica and Helvetica-Bold,
+// which every PDF reader has, so no fonts need to be embedded.
+//
+// Positions and sizes are given in points (1/72 inch) measured from the
+// top left corner of the page.
+package pdf
+
+import (
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"strings"
+
+	"golang.org/x/text/encoding/charmap"
+)
+
+// Sizes of A4 pages in points.
+const (
+	A4Width  = 595.28
+	A4Height = 841.89
+)
+
+// Font is one of the standard fonts.
+type Font int
+
+const (
+	Helvetica Font = iota
+	HelveticaBold
+)
+
+var fontNames = [...]string{"Helvetica", "Helvetica-Bold"}
+
+// Document is a PDF document being drawn page by page.
+type Document struct {
+	Width, Height float64
+	// Title is written to the document information.
+	Title string
+
+	pages []*bytes.Buffer
+	page  int
+}
+
+// New returns an empty document with A4 portrait pages.
+func New() *Document {
+	return &Document{Width: A4Width, Height: A4Height}
+}
+
+// AddPage adds a page to the document. It becomes the current page.
+func (d *Document) AddPage() {
+	d.pages = append(d.pages, &bytes.Buffer{})
+	d.page = len(d.pages) - 1
+}
+
+// PageCount returns the number of pages.
+func (d *Document) PageCount() int {
+	return len(d.pages)
+}
+
+// SetPage makes the page with the index current, e.g. to add page numbers
+// when all pages are drawn.
+func (d *Document) SetPage(index int) {
+	d.page = index
+}
+
+func (d *Document) content() *bytes.Buffer {
+	if len(d.pages) == 0 {
+		d.AddPage()
+	}
+	return d.pages[d.page]
+}
+
+// Text draws the text with its baseline at y.
+func (d *Document) Text(x, y float64, font Font, size float64, text string) {
+	fmt.Fprintf(d.content(), "BT /F%d %s Tf %s %s Td (%s) Tj ET\n",
+		font+1, number(size), number(x), number(d.Height-y), escape(text))
+}
+
+// TextRight draws the text ending at x.
+func (d *Document) TextRight(x, y float64, font Font, size float64, text string) {
+	d.Text(x-Width(font, size, text), y, font, size, text)
+}
+
+// Line draws a black line.
+func (d *Document) Line(x1, y1, x2, y2, width float64) {
+	fmt.Fprintf(d.content(), "%s w 0 G %s %s m %s %s l S\n",
+		number(width), number(x1), number(d.Height-y1), number(x2), number(d.Height-y2))
+}
+
+// Rect fills a rectangle with a gray level between 0 (black) and 1 (white).
+func (d *Document) Rect(x, y, width, height, gray float64) {
+	fmt.Fprintf(d.content(), "%s g %s %s %s %s re f 0 g\n",
+		number(gray), number(x), number(d.Height-y-height), number(width), number(height))
+}
+
+// WriteTo writes the document in PDF format.
+func (d *Document) WriteTo(w io.Writer) (int64, error) {
+	if len(d.pages) == 0 {
+		d.AddPage()
+	}
+
+	var out bytes.Buffer
+	var offsets []int
+	object := func(format string, args ...interface{}) {
+		offsets = append(offsets, out.Len())
+		fmt.Fprintf(&out, "%d 0 obj\n", len(offsets))
+		fmt.Fprintf(&out, format, args...)
+		out.WriteString("\nendobj\n")
+	}
+
+	// Objects 1 and 2 are catalog and page tree, followed by the fonts, the
+	// information and two objects per page: the page and its content.
+	const fonts = len(fontNames)
+	info, firstPage := 3+fonts, 4+fonts
+	var kids []string
+	for i := range d.pages {
+		kids = append(kids, fmt.Sprintf("%d 0 R", firstPage+2*i))
+	}
+
+	out.WriteString("%PDF-1.4\n%\xe2\xe3\xcf\xd3\n")
+	object("<< /Type /Catalog /Pages 2 0 R >>")
+	object("<< /Type /Pages /Kids [%s] /Count %d /MediaBox [0 0 %s %s] >>",
+		strings.Join(kids, " "), len(d.pages), number(d.Width), number(d.Height))
+	var resources []string
+	for i, name := range fontNames {
+		object("<< /Type /Font /Subtype /Type1 /BaseFont /%s /Encoding /WinAnsiEncoding >>", name)
+		resources = append(resources, fmt.Sprintf("/F%d %d 0 R", i+1, 3+i))
+	}
+	object("<< /Title (%s) /Producer (vtr) >>", escape(d.Title))
+
+	for i, page := range d.pages {
+		object("<< /Type /Page /Parent 2 0 R /Resources << /Font << %s >> >> /Contents %d 0 R >>",
+			strings.Join(resources, " "), firstPage+2*i+1)
+
+		var compressed bytes.Buffer
+		z := zlib.NewWriter(&compressed)
+		z.Write(page.Bytes())
+		z.Close()
+		object("<< /Length %d /Filter /FlateDecode >>\nstream\n%s\nendstream", compressed.Len(), compressed.Bytes())
+	}
+
+	xref := out.Len()
+	fmt.Fprintf(&out, "xref\n0 %d\n0000000000 65535 f \n", len(offsets)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(&out, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&out, "trailer\n<< /Size %d /Root 1 0 R /Info %d 0 R >>\nstartxref\n%d\n%%%%EOF\n",
+		len(offsets)+1, info, xref)
+
+	n, err := w.Write(out.Bytes())
+	return int64(n), err
+}
+
+// number formats a number with at most two decimals.
+func number(f float64) string {
+	s := strings.TrimRight(fmt.Sprintf("%.2f", f), "0")
+	return strings.TrimSuffix(s, ".")
+}
+
+// escape encodes the text in WinAnsiEncoding (Windows-1252) as PDF string.
+// Characters which can't be encoded are replaced by "?".
+func escape(text string) string {
+	var b strings.Builder
+	for _, r := range text {
+		c, ok := charmap.Windows1252.EncodeRune(r)
+		if !ok {
+			c = '?'
+		}
+		switch c {
+		case '(', ')', '\\':
+			b.WriteByte('\\')
+			b.WriteByte(c)
+		case '\n', '\r', '\t':
+			b.WriteByte(' ')
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,77 @@
+package pdf
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strconv"
+	"testing"
+)
+
+func TestWidth(t *testing.T) {
+	if w := Width(Helvetica, 10, "Hallo"); w != 22.78 {
+		t.Errorf("Width of Hallo is %v, expected 22.78.", w)
+	}
+	if Width(Helvetica, 10, "Müller") != Width(Helvetica, 10, "Muller") {
+		t.Error("Umlaut isn't as wide as its base letter.")
+	}
+	for _, font := range []Font{Helvetica, HelveticaBold} {
+		if w := Width(font, 1000, "~"); w != 584 {
+			t.Errorf("Width of ~ in %s is %v, expected 584.", fontNames[font], w)
+		}
+	}
+	if Width(HelveticaBold, 10, "m") <= Width(Helvetica, 10, "m") {
+		t.Error("Bold isn't wider.")
+	}
+}
+
+func TestWrap(t *testing.T) {
+	lines := Wrap(Helvetica, 10, "Aufgaben bei Frau Müller abholen", 60)
+	if len(lines) < 2 {
+		t.Fatalf("Text wasn't wrapped: %q", lines)
+	}
+	for _, line := range lines {
+		if Width(Helvetica, 10, line) > 60 {
+			t.Errorf("Line %q is too wide.", line)
+		}
+	}
+
+	lines = Wrap(Helvetica, 10, "Donaudampfschifffahrtsgesellschaft", 50)
+	if len(lines) < 2 {
+		t.Errorf("Long word wasn't broken: %q", lines)
+	}
+}
+
+func TestWriteTo(t *testing.T) {
+	d := New()
+	d.Title = "Vertretungsplan (Test)"
+	d.AddPage()
+	d.Text(50, 50, HelveticaBold, 14, "Vertretungsplan für Mittwoch")
+	d.Line(50, 55, 500, 55, 0.5)
+	d.AddPage()
+	d.Rect(50, 50, 100, 20, 0.9)
+
+	var out bytes.Buffer
+	if _, err := d.WriteTo(&out); err != nil {
+		t.Fatal(err)
+	}
+	pdf := out.Bytes()
+	if !bytes.HasPrefix(pdf, []byte("%PDF-1.4")) || !bytes.HasSuffix(pdf, []byte("%%EOF\n")) {
+		t.Error("Missing header or trailer.")
+	}
+	if !bytes.Contains(pdf, []byte("/Count 2")) {
+		t.Error("Document doesn't have two pages.")
+	}
+
+	// Every offset in the cross-reference table has to point to its object.
+	xref := regexp.MustCompile(`(\d{10}) 00000 n`).FindAllSubmatch(pdf, -1)
+	if len(xref) != 9 {
+		t.Fatalf("%d objects in cross-reference table, expected 9.", len(xref))
+	}
+	for i, entry := range xref {
+		offset, _ := strconv.Atoi(string(entry[1]))
+		if !bytes.HasPrefix(pdf[offset:], []byte(fmt.Sprintf("%d 0 obj", i+1))) {
+			t.Errorf("Offset of object %d is wrong.", i+1)
+		}
+	}
+}
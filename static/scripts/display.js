@@ -0,0 +1,184 @@
+// Shows the current plan on the signage displays. The substitutions of each
+// day are split into pages fitting the screen, which are shown one after
+// another. The plan is reloaded in the background when a new one is announced.
+(function() {
+	var columns = parseInt(document.body.getAttribute('data-columns'), 10);
+	var duration = parseInt(document.body.getAttribute('data-duration'), 10) * 1000;
+	var weekdays = ['Sonntag', 'Montag', 'Dienstag', 'Mittwoch', 'Donnerstag', 'Freitag', 'Samstag'];
+
+	var planJSON = '';
+	var slides = [];
+	var current = 0;
+
+	function pad(n) {
+		return (n < 10 ? '0' : '') + n;
+	}
+
+	function formatDate(date) {
+		return pad(date.getDate()) + '.' + pad(date.getMonth() + 1) + '.' + date.getFullYear();
+	}
+
+	function fullName(teacher) {
+		if (!teacher || !teacher.Name) {
+			return teacher ? teacher.Short : '';
+		}
+		if (teacher.Sex === 'm') {
+			return 'Herr ' + teacher.Name;
+		}
+		if (teacher.Sex === 'w') {
+			return 'Frau ' + teacher.Name;
+		}
+		return teacher.Name;
+	}
+
+	function cell(row, text) {
+		var td = document.createElement('td');
+		td.textContent = text || '';
+		row.appendChild(td);
+	}
+
+	function table(substitutions) {
+		var t = document.createElement('table');
+		var head = document.createElement('tr');
+		['Klasse', 'Stunde', 'Vertretung', 'Fach', 'Art', 'Hinweis'].forEach(function(title) {
+			var th = document.createElement('th');
+			th.textContent = title;
+			head.appendChild(th);
+		});
+		t.appendChild(head);
+		substitutions.forEach(function(s) {
+			var row = document.createElement('tr');
+			cell(row, s.Class);
+			cell(row, s.Period);
+			cell(row, fullName(s.SubstTeacher));
+			cell(row, s.InstdSubject.Name || s.InstdSubject.Short);
+			cell(row, s.Kind);
+			cell(row, s.Text);
+			t.appendChild(row);
+		});
+		return t;
+	}
+
+	// rowsPerColumn measures how many rows fit on the screen below the news.
+	function rowsPerColumn(news) {
+		showNews(news);
+		var container = document.getElementById('columns');
+		container.innerHTML = '';
+		var probe = table([{Class: 'X', InstdSubject: {}}]);
+		container.appendChild(probe);
+		var rowHeight = probe.rows[1].offsetHeight;
+		var available = window.innerHeight - container.offsetTop
+			- document.getElementById('footer').offsetHeight - probe.rows[0].offsetHeight;
+		container.innerHTML = '';
+		return Math.max(1, Math.floor(available / rowHeight));
+	}
+
+	function buildSlides(plan) {
+		var built = [];
+		(plan.Parts || []).forEach(function(part) {
+			var news = part.News || [];
+			var substitutions = part.Substitutions || [];
+			var perPage = rowsPerColumn(news) * columns;
+			var pages = Math.max(1, Math.ceil(substitutions.length / perPage));
+			for (var page = 0; page < pages; page++) {
+				built.push({
+					day: new Date(part.Day),
+					news: news,
+					substitutions: substitutions.slice(page * perPage, (page + 1) * perPage),
+					perColumn: perPage / columns,
+					page: page + 1,
+					pages: pages
+				});
+			}
+		});
+		return built;
+	}
+
+	function showNews(news) {
+		var list = document.getElementById('news');
+		list.innerHTML = '';
+		news.forEach(function(line) {
+			var li = document.createElement('li');
+			li.textContent = line;
+			list.appendChild(li);
+		});
+		list.style.display = news.length ? '' : 'none';
+	}
+
+	function show(slide) {
+		document.getElementById('day').textContent = weekdays[slide.day.getDay()] + ', ' + formatDate(slide.day);
+		document.getElementById('page').textContent = slide.pages > 1 ? 'Seite ' + slide.page + ' / ' + slide.pages : '';
+		showNews(slide.news);
+
+		var container = document.getElementById('columns');
+		container.innerHTML = '';
+		for (var c = 0; c < columns; c++) {
+			var part = slide.substitutions.slice(c * slide.perColumn, (c + 1) * slide.perColumn);
+			var column = document.createElement('div');
+			column.className = 'column';
+			column.style.width = (100 / columns) + '%';
+			if (part.length) {
+				column.appendChild(table(part));
+			} else if (c === 0) {
+				column.textContent = 'Keine Vertretungen.';
+			}
+			container.appendChild(column);
+		}
+	}
+
+	function next() {
+		if (slides.length) {
+			current = (current + 1) % slides.length;
+			show(slides[current]);
+		}
+	}
+
+	function update(json) {
+		if (json === planJSON) {
+			return;
+		}
+		planJSON = json;
+		var plan = JSON.parse(json);
+		document.getElementById('created').textContent = formatDate(new Date(plan.Created)) + ' '
+			+ pad(new Date(plan.Created).getHours()) + ':' + pad(new Date(plan.Created).getMinutes());
+		slides = buildSlides(plan);
+		current = 0;
+		if (slides.length) {
+			show(slides[0]);
+		}
+	}
+
+	function load() {
+		var request = new XMLHttpRequest();
+		request.open('GET', '/plan');
+		request.onload = function() {
+			if (request.status === 200) {
+				update(request.responseText);
+			}
+		};
+		request.send();
+	}
+
+	function tick() {
+		var now = new Date();
+		document.getElementById('clock').textContent = pad(now.getHours()) + ':' + pad(now.getMinutes());
+	}
+
+	tick();
+	setInterval(tick, 1000);
+	load();
+	// New plans are announced by the event stream. Polling is kept as a
+	// fallback for browsers without EventSource and for lost connections.
+	if (window.EventSource) {
+		new EventSource('/plan/events').addEventListener('plan', load);
+		setInterval(load, 300000);
+	} else {
+		setInterval(load, 60000);
+	}
+	setInterval(next, duration);
+	window.addEventListener('resize', function() {
+		var json = planJSON;
+		planJSON = '';
+		update(json);
+	});
+})();
@@ -0,0 +1,35 @@
+// Offers to undo a destructive action. The admin pages call showUndo with the
+// URL a DELETE request served, which is valid for ten minutes.
+var undoDuration = 10 * 60 * 1000;
+
+function showUndo(text, url) {
+	if (!url) {
+		return;
+	}
+	var toast = document.createElement('div');
+	toast.className = 'toast';
+	toast.appendChild(document.createTextNode(text + ' '));
+
+	var button = document.createElement('button');
+	button.appendChild(document.createTextNode('Rückgängig'));
+	button.onclick = function() {
+		var request = new XMLHttpRequest();
+		request.open('POST', url);
+		request.onload = function() {
+			if (request.status >= 400) {
+				alert(request.responseText);
+			}
+			location.reload();
+		};
+		request.send();
+		button.disabled = true;
+	};
+	toast.appendChild(button);
+
+	document.body.appendChild(toast);
+	setTimeout(function() {
+		if (toast.parentNode) {
+			toast.parentNode.removeChild(toast);
+		}
+	}, undoDuration);
+}